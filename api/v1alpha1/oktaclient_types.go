@@ -23,9 +23,107 @@ import (
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
+// ApplicationType is the OAuth client type of an Okta application.
+// +kubebuilder:validation:Enum=web;browser;native;service
+type ApplicationType string
+
+const (
+	ApplicationTypeWeb     ApplicationType = "web"
+	ApplicationTypeBrowser ApplicationType = "browser"
+	ApplicationTypeNative  ApplicationType = "native"
+	ApplicationTypeService ApplicationType = "service"
+)
+
+// GrantType is an OAuth grant type.
+// +kubebuilder:validation:Enum=authorization_code;implicit;password;refresh_token;client_credentials;urn:ietf:params:oauth:grant-type:device_code
+type GrantType string
+
+// ResponseType is an OAuth response type.
+// +kubebuilder:validation:Enum=code;token;id_token
+type ResponseType string
+
+// TokenEndpointAuthMethod is the method used by a client to authenticate at the token endpoint.
+// +kubebuilder:validation:Enum=none;client_secret_basic;client_secret_post;client_secret_jwt;private_key_jwt
+type TokenEndpointAuthMethod string
+
+// DeletionPolicy defines what happens to the Okta application and its trusted origins, once the OktaClient is deleted.
+// +kubebuilder:validation:Enum=Delete;Deactivate;Orphan
+type DeletionPolicy string
+
+const (
+	DeletionPolicyDelete     DeletionPolicy = "Delete"
+	DeletionPolicyDeactivate DeletionPolicy = "Deactivate"
+	DeletionPolicyOrphan     DeletionPolicy = "Orphan"
+)
+
+// DriftPolicy defines what happens, if the Okta application, its trusted origins or group assignment have been changed
+// outside of the operator.
+// +kubebuilder:validation:Enum=Correct;Report
+type DriftPolicy string
+
+const (
+	DriftPolicyCorrect DriftPolicy = "Correct"
+	DriftPolicyReport  DriftPolicy = "Report"
+)
+
+// SecretDeletionPolicy defines what happens to the generated secret, once its OktaClient is deleted.
+// +kubebuilder:validation:Enum=Retain;Delete
+type SecretDeletionPolicy string
+
+const (
+	SecretDeletionPolicyRetain SecretDeletionPolicy = "Retain"
+	SecretDeletionPolicyDelete SecretDeletionPolicy = "Delete"
+)
+
+// PrivateKey configures the key pairs generated by the operator for private key JWT client authentication.
+type PrivateKey struct {
+	// Algorithm of the generated key pairs. Defaults to RS256.
+	// +kubebuilder:validation:Enum=RS256;ES256
+	// +kubebuilder:default=RS256
+	Algorithm string `json:"algorithm,omitempty"`
+
+	// RotationInterval after which a new key pair is generated. Key pairs are not rotated, if unset.
+	RotationInterval *metav1.Duration `json:"rotationInterval,omitempty"`
+
+	// GracePeriod during which the previous public key stays registered after a rotation. Defaults to 24h.
+	GracePeriod *metav1.Duration `json:"gracePeriod,omitempty"`
+}
+
+// SecretRotation configures the scheduled rotation of the client secret.
+type SecretRotation struct {
+	// Interval after which a new client secret is created.
+	Interval metav1.Duration `json:"interval"`
+
+	// GracePeriod during which the previous client secret stays active after a rotation. Defaults to 24h.
+	GracePeriod *metav1.Duration `json:"gracePeriod,omitempty"`
+}
+
+// SecretTemplate configures the contents of the generated secret.
+type SecretTemplate struct {
+	// Data maps secret keys to Go templates rendering their values. Templates can use .ApplicationID, .ClientID,
+	// .ClientSecret, .PrivateKey, .KeyID, .OrgURL, .Issuer, .DiscoveryURL, .AuthorizationEndpoint, .TokenEndpoint,
+	// .UserinfoEndpoint, .JWKSURI, .EndSessionEndpoint, .RedirectURIs and .PostLogoutRedirectURIs. Templates replace
+	// default keys of the same name, except those of the client secret and the private key.
+	Data map[string]string `json:"data,omitempty"`
+
+	// Keys renames default keys (e.g. OKTA_CLIENT_ID) of the generated secret. Default keys renamed to "" are omitted.
+	// The keys of the client secret and the private key cannot be omitted.
+	Keys map[string]string `json:"keys,omitempty"`
+
+	// Labels of the generated secret. Labels removed from the template are removed from the secret.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations of the generated secret. Annotations removed from the template are removed from the secret.
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
 // OktaClientSpec defines the desired state of OktaClient
 type OktaClientSpec struct {
 
+	// OrgRef references the Okta organization the application is managed in. Defaults to the organization configured
+	// for the operator.
+	OrgRef *OrgReference `json:"orgRef,omitempty"`
+
 	// +kubebuilder:validation:MinLength=1
 	Name string `json:"name,omitempty"`
 
@@ -44,6 +142,89 @@ type OktaClientSpec struct {
 	// +kubebuilder:validation:MaxLength=30
 	// +kubebuilder:validation:MinLength=1
 	GroupId string `json:"groupId,omitempty"`
+
+	// ApplicationType of the Okta application. Defaults to web.
+	// +kubebuilder:default=web
+	ApplicationType ApplicationType `json:"applicationType,omitempty"`
+
+	// GrantTypes of the Okta application. Defaults depend on the application type.
+	// +kubebuilder:validation:MinItems=1
+	GrantTypes []GrantType `json:"grantTypes,omitempty"`
+
+	// ResponseTypes of the Okta application. Defaults depend on the application type.
+	// +kubebuilder:validation:MinItems=1
+	ResponseTypes []ResponseType `json:"responseTypes,omitempty"`
+
+	// TokenEndpointAuthMethod of the Okta application. Defaults depend on the application type.
+	TokenEndpointAuthMethod TokenEndpointAuthMethod `json:"tokenEndpointAuthMethod,omitempty"`
+
+	// PrivateKey configures the key pairs used, if the token endpoint auth method is private_key_jwt.
+	PrivateKey *PrivateKey `json:"privateKey,omitempty"`
+
+	// SecretRotation configures the scheduled rotation of the client secret. Client secrets are not rotated, if unset.
+	SecretRotation *SecretRotation `json:"secretRotation,omitempty"`
+
+	// DeletionPolicy defines whether the Okta application and its trusted origins are deleted, deactivated or left
+	// untouched, once the OktaClient is deleted. Defaults to Orphan for adopted applications and to the deletion policy
+	// configured for the operator otherwise.
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// DriftPolicy defines whether changes made to the Okta application, its trusted origins or group assignment outside
+	// of the operator are corrected or only reported by the Drifted condition. Defaults to Correct.
+	// +kubebuilder:default=Correct
+	DriftPolicy DriftPolicy `json:"driftPolicy,omitempty"`
+
+	// SecretDeletionPolicy defines whether the generated secret is retained or deleted together with the OktaClient.
+	// Defaults to Retain.
+	// +kubebuilder:default=Retain
+	SecretDeletionPolicy SecretDeletionPolicy `json:"secretDeletionPolicy,omitempty"`
+
+	// SecretTemplate configures the keys, labels and annotations of the generated secret.
+	SecretTemplate *SecretTemplate `json:"secretTemplate,omitempty"`
+
+	// AuthorizationServerID of the custom authorization server issuing tokens for the application, e.g. default. The
+	// org authorization server is used, if unset.
+	// +kubebuilder:validation:MinLength=1
+	AuthorizationServerID string `json:"authorizationServerId,omitempty"`
+
+	// ExistingApplicationID of an Okta application to adopt instead of creating a new one. Its settings are reconciled
+	// from the spec.
+	// +kubebuilder:validation:MinLength=1
+	ExistingApplicationID string `json:"existingApplicationId,omitempty"`
+
+	// AllowNewSecret allows replacing the client secrets of an adopted application, if the secret does not contain its
+	// client secret. Workloads still using the previous client secrets can no longer authenticate.
+	AllowNewSecret bool `json:"allowNewSecret,omitempty"`
+}
+
+// PrivateKeyStatus describes the key pairs generated for private key JWT client authentication.
+type PrivateKeyStatus struct {
+	// KeyID of the current key pair.
+	KeyID string `json:"keyId,omitempty"`
+
+	// RotatedAt is the time the current key pair was generated.
+	RotatedAt *metav1.Time `json:"rotatedAt,omitempty"`
+
+	// PreviousKeyID of the key pair replaced by the last rotation.
+	PreviousKeyID string `json:"previousKeyId,omitempty"`
+
+	// PreviousKeyExpiresAt is the time the previous public key is removed from the application.
+	PreviousKeyExpiresAt *metav1.Time `json:"previousKeyExpiresAt,omitempty"`
+}
+
+// SecretRotationStatus describes the client secrets created by scheduled rotations.
+type SecretRotationStatus struct {
+	// SecretID of the current client secret.
+	SecretID string `json:"secretId,omitempty"`
+
+	// RotatedAt is the time the current client secret was created.
+	RotatedAt *metav1.Time `json:"rotatedAt,omitempty"`
+
+	// PreviousSecretID of the client secret replaced by the last rotation.
+	PreviousSecretID string `json:"previousSecretId,omitempty"`
+
+	// PreviousSecretExpiresAt is the time the previous client secret is deactivated and deleted.
+	PreviousSecretExpiresAt *metav1.Time `json:"previousSecretExpiresAt,omitempty"`
 }
 
 // OktaClientStatus defines the observed state of OktaClient
@@ -51,10 +232,53 @@ type OktaClientStatus struct {
 
 	// Conditions represent the latest available observations of an object's state
 	Conditions []metav1.Condition `json:"conditions"`
+
+	// ObservedGeneration is the most recent generation observed by the operator.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// SyncedGeneration is the most recent generation that has been synced with Okta. Once the spec has been synced,
+	// differences between the spec and Okta are considered drift.
+	SyncedGeneration int64 `json:"syncedGeneration,omitempty"`
+
+	// ApplicationID is the ID of the Okta application.
+	ApplicationID string `json:"applicationId,omitempty"`
+
+	// ClientID is the OAuth client ID of the Okta application.
+	ClientID string `json:"clientId,omitempty"`
+
+	// Adopted is true, if the Okta application has been adopted by its ID instead of being created by the operator.
+	Adopted bool `json:"adopted,omitempty"`
+
+	// SecretName is the name of the secret containing the client credentials.
+	SecretName string `json:"secretName,omitempty"`
+
+	// Issuer of the tokens for the Okta application.
+	Issuer string `json:"issuer,omitempty"`
+
+	// OrgURL of the Okta organization the application and trusted origins are managed in.
+	OrgURL string `json:"orgURL,omitempty"`
+
+	// TrustedOrigins are the trusted origins managed by the operator. Origins removed from the spec are deleted in Okta.
+	TrustedOrigins []string `json:"trustedOrigins,omitempty"`
+
+	// PrivateKey describes the key pairs used for private key JWT client authentication.
+	PrivateKey *PrivateKeyStatus `json:"privateKey,omitempty"`
+
+	// SecretRotation describes the client secrets created by scheduled rotations.
+	SecretRotation *SecretRotationStatus `json:"secretRotation,omitempty"`
+
+	// LastSecretRotationRequest is the value of the okta.jaconi.io/rotate-secret annotation handled last. Changing the
+	// annotation to a different value requests another rotation of the client secret.
+	LastSecretRotationRequest string `json:"lastSecretRotationRequest,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Application",type=string,JSONPath=`.spec.name`
+//+kubebuilder:printcolumn:name="Client ID",type=string,JSONPath=`.status.clientId`
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // OktaClient is the Schema for the oktaclients API
 type OktaClient struct {
@@ -0,0 +1,153 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	OktaOrgKind        = "OktaOrg"
+	ClusterOktaOrgKind = "ClusterOktaOrg"
+
+	// DefaultCredentialsKey is the key of the API token in the credentials secret of an Okta organization.
+	DefaultCredentialsKey = "OKTA_CLIENT_TOKEN"
+
+	// DefaultPrivateKeyKey is the key of the private key in the private key secret of an Okta service application.
+	DefaultPrivateKeyKey = "OKTA_CLIENT_PRIVATEKEY"
+)
+
+// CredentialsSecretReference references the secret containing the API token of an Okta organization.
+type CredentialsSecretReference struct {
+	// Name of the secret.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Namespace of the secret. Required by ClusterOktaOrgs. The secret of an OktaOrg is always in its namespace.
+	Namespace string `json:"namespace,omitempty"`
+
+	// Key of the API token in the secret. Defaults to OKTA_CLIENT_TOKEN.
+	// +kubebuilder:default=OKTA_CLIENT_TOKEN
+	Key string `json:"key,omitempty"`
+}
+
+// PrivateKeySecretReference references the secret containing the private key of an Okta service application.
+type PrivateKeySecretReference struct {
+	// Name of the secret.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Namespace of the secret. Required by ClusterOktaOrgs. The secret of an OktaOrg is always in its namespace.
+	Namespace string `json:"namespace,omitempty"`
+
+	// Key of the PEM encoded private key in the secret. Defaults to OKTA_CLIENT_PRIVATEKEY.
+	// +kubebuilder:default=OKTA_CLIENT_PRIVATEKEY
+	Key string `json:"key,omitempty"`
+}
+
+// OAuthCredentials configure the Okta service application the operator authenticates as, using OAuth 2.0 client
+// credentials with private key JWT.
+type OAuthCredentials struct {
+	// ClientID of the service application.
+	// +kubebuilder:validation:MinLength=1
+	ClientID string `json:"clientId"`
+
+	// PrivateKeySecretRef references the secret containing the private key of the service application.
+	PrivateKeySecretRef PrivateKeySecretReference `json:"privateKeySecretRef"`
+
+	// PrivateKeyID is the ID of the public key registered with the service application. It is optional, if the
+	// service application has a single public key.
+	PrivateKeyID string `json:"privateKeyId,omitempty"`
+
+	// Scopes requested for the access tokens. Defaults to okta.apps.manage, okta.trustedOrigins.manage and
+	// okta.groups.manage.
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// OktaOrgSpec defines the Okta organization and the credentials used to manage its applications. Either an API token
+// or OAuth credentials are required.
+type OktaOrgSpec struct {
+	// OrgURL of the Okta organization, e.g. https://example.okta.com.
+	// +kubebuilder:validation:Pattern=`^https://`
+	OrgURL string `json:"orgUrl"`
+
+	// CredentialsSecretRef references the secret containing the API token.
+	CredentialsSecretRef *CredentialsSecretReference `json:"credentialsSecretRef,omitempty"`
+
+	// OAuth configures the Okta service application the operator authenticates as instead of using an API token.
+	OAuth *OAuthCredentials `json:"oauth,omitempty"`
+}
+
+// OrgReference references the OktaOrg or ClusterOktaOrg an OktaClient is managed in.
+type OrgReference struct {
+	// Kind of the referenced organization. Defaults to OktaOrg.
+	// +kubebuilder:validation:Enum=OktaOrg;ClusterOktaOrg
+	// +kubebuilder:default=OktaOrg
+	Kind string `json:"kind,omitempty"`
+
+	// Name of the referenced organization. An OktaOrg must be in the namespace of the OktaClient.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:printcolumn:name="URL",type=string,JSONPath=`.spec.orgUrl`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// OktaOrg is the Schema for the oktaorgs API. It connects the OktaClients of its namespace to an Okta organization.
+type OktaOrg struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec OktaOrgSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// OktaOrgList contains a list of OktaOrg
+type OktaOrgList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []OktaOrg `json:"items"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="URL",type=string,JSONPath=`.spec.orgUrl`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ClusterOktaOrg is the Schema for the clusteroktaorgs API. It connects the OktaClients of all namespaces to an Okta
+// organization.
+type ClusterOktaOrg struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec OktaOrgSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ClusterOktaOrgList contains a list of ClusterOktaOrg
+type ClusterOktaOrgList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterOktaOrg `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&OktaOrg{}, &OktaOrgList{}, &ClusterOktaOrg{}, &ClusterOktaOrgList{})
+}
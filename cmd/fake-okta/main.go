@@ -0,0 +1,53 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// fake-okta serves a fake Okta organization for local development, e.g. in a kind cluster. See README.md.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/jaconi-io/okta-operator/okta/oktatest"
+)
+
+func main() {
+	var bindAddress string
+	var token string
+	var rateLimit int
+	var tlsCertFile string
+	var tlsKeyFile string
+	flag.StringVar(&bindAddress, "bind-address", ":8090", "The address the fake Okta API binds to.")
+	flag.StringVar(&token, "token", "", "The API token requests must be authorized with. Any token is accepted, if empty.")
+	flag.IntVar(&rateLimit, "rate-limit", 0, "The number of requests per minute, before requests are rejected. "+
+		"Requests are not limited, if zero.")
+	flag.StringVar(&tlsCertFile, "tls-cert-file", "", "The TLS certificate to serve HTTPS with. HTTP is served, if empty.")
+	flag.StringVar(&tlsKeyFile, "tls-key-file", "", "The private key of the TLS certificate.")
+	flag.Parse()
+
+	server := oktatest.NewServer(token)
+	server.RateLimit = rateLimit
+
+	log.Printf("serving fake Okta API on %s", bindAddress)
+	var err error
+	if tlsCertFile != "" {
+		err = http.ListenAndServeTLS(bindAddress, tlsCertFile, tlsKeyFile, server)
+	} else {
+		err = http.ListenAndServe(bindAddress, server)
+	}
+	log.Fatalf("failed to serve fake Okta API: %v", err)
+}
@@ -0,0 +1,164 @@
+package controllers
+
+import (
+	"context"
+	oktav1alpha1 "github.com/jaconi-io/okta-operator/api/v1alpha1"
+	"github.com/jaconi-io/okta-operator/okta"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"time"
+)
+
+const metricsCollectTimeout = 10 * time.Second
+
+var (
+	reconcileOutcomes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "okta_operator_reconcile_outcomes_total",
+		Help: "Number of OktaClient reconciliations by the reason recorded in the Ready condition.",
+	}, []string{"reason"})
+
+	secretRotations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "okta_operator_secret_rotations_total",
+		Help: "Number of client secret rotations by trigger, either scheduled or requested by annotation.",
+	}, []string{"trigger"})
+
+	driftDetections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "okta_operator_drift_detections_total",
+		Help: "Number of OktaClient reconciliations detecting drift of the Okta application by drift policy.",
+	}, []string{"policy"})
+
+	managedApplicationsDesc = prometheus.NewDesc("okta_operator_managed_applications",
+		"Number of Okta applications managed by OktaClients by Okta organization.", []string{"org"}, nil)
+	managedTrustedOriginsDesc = prometheus.NewDesc("okta_operator_managed_trusted_origins",
+		"Number of trusted origins managed by OktaClients by Okta organization.", []string{"org"}, nil)
+)
+
+func init() {
+	metrics.Registry.MustRegister(reconcileOutcomes, secretRotations, driftDetections)
+	metrics.Registry.MustRegister(okta.Collectors()...)
+}
+
+// ManagedResourcesCollector reports the number of Okta applications and trusted origins managed by OktaClients per
+// Okta organization. Like the Okta API metrics, organizations are identified by their host. The OktaClients are listed
+// on every scrape, so the reader should be backed by a cache.
+type ManagedResourcesCollector struct {
+	reader client.Reader
+
+	// defaultOrg is the Okta organization of OktaClients without an organization reference. It may be nil.
+	defaultOrg okta.API
+}
+
+var _ prometheus.Collector = &ManagedResourcesCollector{}
+
+// NewManagedResourcesCollector returns a collector listing the OktaClients with the given reader. OktaClients without
+// an organization reference are counted for the given default organization, if any.
+func NewManagedResourcesCollector(reader client.Reader, defaultOrg okta.API) *ManagedResourcesCollector {
+	return &ManagedResourcesCollector{reader: reader, defaultOrg: defaultOrg}
+}
+
+// Describe implements prometheus.Collector.
+func (c *ManagedResourcesCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- managedApplicationsDesc
+	ch <- managedTrustedOriginsDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c *ManagedResourcesCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), metricsCollectTimeout)
+	defer cancel()
+
+	applications, trustedOrigins, err := c.count(ctx)
+	if err != nil {
+		ctrllog.FromContext(ctx).Error(err, "Failed to collect metrics of managed Okta resources")
+		ch <- prometheus.NewInvalidMetric(managedApplicationsDesc, err)
+		return
+	}
+
+	for org, count := range applications {
+		ch <- prometheus.MustNewConstMetric(managedApplicationsDesc, prometheus.GaugeValue, float64(count), org)
+	}
+	for org, count := range trustedOrigins {
+		ch <- prometheus.MustNewConstMetric(managedTrustedOriginsDesc, prometheus.GaugeValue, float64(count), org)
+	}
+}
+
+// count returns the number of applications and trusted origins managed by OktaClients per organization host. Trusted
+// origins shared by several OktaClients are counted once. OktaClients whose organization cannot be resolved are
+// ignored.
+func (c *ManagedResourcesCollector) count(ctx context.Context) (map[string]int, map[string]int, error) {
+	oktaClients := &oktav1alpha1.OktaClientList{}
+	err := c.reader.List(ctx, oktaClients)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	applications := map[string]int{}
+	origins := map[string]map[string]bool{}
+	orgURLs := map[types.NamespacedName]string{}
+	for i := range oktaClients.Items {
+		oktaClient := &oktaClients.Items[i]
+		if oktaClient.Status.ApplicationID == "" {
+			continue
+		}
+
+		org, ok := c.orgURL(oktaClient, ctx, orgURLs)
+		if !ok {
+			continue
+		}
+		host := urlHost(org)
+		applications[host]++
+		if origins[host] == nil {
+			origins[host] = map[string]bool{}
+		}
+		for _, origin := range oktaClient.Status.TrustedOrigins {
+			origins[host][origin] = true
+		}
+	}
+
+	trustedOrigins := map[string]int{}
+	for host, hostOrigins := range origins {
+		trustedOrigins[host] = len(hostOrigins)
+	}
+	return applications, trustedOrigins, nil
+}
+
+// orgURL returns the URL of the Okta organization of the OktaClient. Resolved organization references are cached in
+// the given map.
+func (c *ManagedResourcesCollector) orgURL(oktaClient *oktav1alpha1.OktaClient, ctx context.Context, orgURLs map[types.NamespacedName]string) (string, bool) {
+	ref := oktaClient.Spec.OrgRef
+	if ref == nil {
+		if c.defaultOrg == nil {
+			return "", false
+		}
+		orgURL := c.defaultOrg.OrgURL()
+		return orgURL, orgURL != ""
+	}
+
+	key := types.NamespacedName{Namespace: oktaClient.Namespace, Name: ref.Name}
+	if ref.Kind == oktav1alpha1.ClusterOktaOrgKind {
+		key.Namespace = ""
+	}
+	if orgURL, ok := orgURLs[key]; ok {
+		return orgURL, orgURL != ""
+	}
+
+	var spec oktav1alpha1.OktaOrgSpec
+	var err error
+	if ref.Kind == oktav1alpha1.ClusterOktaOrgKind {
+		org := &oktav1alpha1.ClusterOktaOrg{}
+		err = c.reader.Get(ctx, key, org)
+		spec = org.Spec
+	} else {
+		org := &oktav1alpha1.OktaOrg{}
+		err = c.reader.Get(ctx, key, org)
+		spec = org.Spec
+	}
+	if err != nil {
+		spec.OrgURL = ""
+	}
+	orgURLs[key] = spec.OrgURL
+	return spec.OrgURL, spec.OrgURL != ""
+}
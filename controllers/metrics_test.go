@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"github.com/jaconi-io/okta-operator/api/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strings"
+	"testing"
+)
+
+func TestManagedResourcesCollector(t *testing.T) {
+	t.Parallel()
+	synced := testAppClient.DeepCopy()
+	synced.Status.ApplicationID = "app"
+	synced.Status.TrustedOrigins = []string{"https://a.example.com", "https://b.example.com"}
+	shared := testAppClient.DeepCopy()
+	shared.Name = "shared"
+	shared.Status.ApplicationID = "shared-app"
+	shared.Status.TrustedOrigins = []string{"https://b.example.com"}
+	pending := testAppClient.DeepCopy()
+	pending.Name = "pending"
+	preview := testAppClient.DeepCopy()
+	preview.Name = "preview"
+	preview.Spec.OrgRef = &v1alpha1.OrgReference{Kind: v1alpha1.ClusterOktaOrgKind, Name: "preview"}
+	preview.Status.ApplicationID = "app"
+	org := &v1alpha1.ClusterOktaOrg{
+		ObjectMeta: metav1.ObjectMeta{Name: "preview"},
+		Spec:       v1alpha1.OktaOrgSpec{OrgURL: "https://preview.example.com/"},
+	}
+
+	collector := NewManagedResourcesCollector(newTestClient(synced, shared, pending, preview, org), newOktaMock())
+	want := `
+# HELP okta_operator_managed_applications Number of Okta applications managed by OktaClients by Okta organization.
+# TYPE okta_operator_managed_applications gauge
+okta_operator_managed_applications{org="example.okta.com"} 2
+okta_operator_managed_applications{org="preview.example.com"} 1
+# HELP okta_operator_managed_trusted_origins Number of trusted origins managed by OktaClients by Okta organization.
+# TYPE okta_operator_managed_trusted_origins gauge
+okta_operator_managed_trusted_origins{org="example.okta.com"} 2
+okta_operator_managed_trusted_origins{org="preview.example.com"} 0
+`
+	err := testutil.CollectAndCompare(collector, strings.NewReader(want))
+	if err != nil {
+		t.Errorf("unexpected metrics: %v", err)
+	}
+}
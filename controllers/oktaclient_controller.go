@@ -20,30 +20,83 @@ import (
 	"context"
 	"fmt"
 	oktav1alpha1 "github.com/jaconi-io/okta-operator/api/v1alpha1"
+	"github.com/jaconi-io/okta-operator/okta"
 	core "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	"strings"
+	"time"
 )
 
 const (
-	finalizerOktaClient        = "okta.jaconi.io/oktaClient"
-	ConditionTypeSynced string = "Ready"
-	ConditionTypeError  string = "Error"
+	finalizerOktaClient           = "okta.jaconi.io/oktaClient"
+	annotationRotateSecret        = "okta.jaconi.io/rotate-secret"
+	ConditionTypeSynced    string = "Ready"
+	ConditionTypeError     string = "Error"
+	ConditionTypeDrifted   string = "Drifted"
+
+	ConditionTypeOktaUnreachable string = "OktaUnreachable"
+
+	ReasonSynced               string = "Synced"
+	ReasonInvalidSpec          string = "InvalidSpec"
+	ReasonTrustedOriginsFailed string = "TrustedOriginsFailed"
+	ReasonApplicationFailed    string = "ApplicationFailed"
+	ReasonRateLimited          string = "RateLimited"
+	ReasonOrgUnavailable       string = "OrgUnavailable"
+	ReasonDriftDetectionFailed string = "DriftDetectionFailed"
+	ReasonNoDrift              string = "NoDrift"
+	ReasonDriftDetected        string = "DriftDetected"
+	ReasonDriftCorrected       string = "DriftCorrected"
+	ReasonOktaReachable        string = "OktaReachable"
+	ReasonOktaUnreachable      string = "OktaUnreachable"
+
+	EventReasonSecretRotated  string = "SecretRotated"
+	EventReasonDriftDetected  string = "DriftDetected"
+	EventReasonDriftCorrected string = "DriftCorrected"
 )
 
 // OktaClientReconciler reconciles a OktaClient object
 type OktaClientReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+
+	// Okta is the API of the Okta organization OktaClients without an organization reference are reconciled with.
+	// OktaClients must reference an organization, if nil.
+	Okta okta.API
+
+	// Orgs creates the APIs of the Okta organizations referenced by OktaClients.
+	Orgs okta.ClientFactory
+
+	// ResyncInterval after which OktaClients are reconciled again to detect changes made in Okta. OktaClients are only
+	// reconciled on changes in Kubernetes, if zero.
+	ResyncInterval time.Duration
+
+	// Events trigger the reconciliation of OktaClients, e.g. those affected by changes received by the EventHook.
+	Events <-chan event.GenericEvent
+
+	// Probe reports whether the default Okta organization is reachable. OktaClients of the default organization are
+	// not reconciled, while it is unreachable. The default organization is not probed, if nil.
+	Probe *OktaProbe
+
+	// DefaultDeletionPolicy applies to OktaClients without a deletion policy. Defaults to Delete.
+	DefaultDeletionPolicy oktav1alpha1.DeletionPolicy
 }
 
 //+kubebuilder:rbac:groups=okta.jaconi.io,resources=oktaclients,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=okta.jaconi.io,resources=oktaclients/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=okta.jaconi.io,resources=oktaclients/finalizers,verbs=update
 //+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -65,6 +118,10 @@ func (r *OktaClientReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	if oktaClient.GetDeletionTimestamp() != nil {
 		if controllerutil.ContainsFinalizer(oktaClient, finalizerOktaClient) {
 			err := r.cleanUp(oktaClient, ctx, req)
+			if retryAfter, rateLimited := okta.RateLimitRetryAfter(err); rateLimited {
+				ctrllog.FromContext(ctx).Info("Okta API rate limit exceeded, retrying clean up later", "retryAfter", retryAfter)
+				return ctrl.Result{RequeueAfter: retryAfter}, nil
+			}
 			if err != nil {
 				return ctrl.Result{}, err
 			}
@@ -87,39 +144,196 @@ func (r *OktaClientReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		}
 	}
 
-	err = updateTrustedOrigins(oktaClient, ctx)
+	err = validateApplicationSettings(applicationSettings(oktaClient))
+	if err != nil {
+		// Retrying does not help. The OktaClient is reconciled again, once its spec changes.
+		err = fmt.Errorf("invalid application settings for oktaClient %q: %w", req.NamespacedName, err)
+		return ctrl.Result{}, reconcile.TerminalError(r.updateStatus(oktaClient, ctx, ReasonInvalidSpec, err))
+	}
+
+	err = validateSecretTemplate(oktaClient.Spec.SecretTemplate)
+	if err != nil {
+		err = fmt.Errorf("invalid secret template for oktaClient %q: %w", req.NamespacedName, err)
+		return ctrl.Result{}, reconcile.TerminalError(r.updateStatus(oktaClient, ctx, ReasonInvalidSpec, err))
+	}
+
+	oktaAPI, err := r.oktaAPI(oktaClient, ctx)
+	if err != nil {
+		err = fmt.Errorf("failed to connect to the Okta organization of oktaClient %q: %w", req.NamespacedName, err)
+		return ctrl.Result{}, r.updateStatus(oktaClient, ctx, ReasonOrgUnavailable, err)
+	}
+	oktaClient.Status.OrgURL = oktaAPI.OrgURL()
+
+	if oktaClient.Spec.OrgRef == nil && r.Probe != nil {
+		if unreachable := r.Probe.Unreachable(); unreachable != nil {
+			// The OktaClient is reconciled again, once the probe succeeds. It is requeued after the probe interval at the
+			// latest, in case the probe does not trigger the reconciliation, e.g. because listing the OktaClients fails.
+			err = fmt.Errorf("failed to reconcile oktaClient %q: %w", req.NamespacedName, unreachable)
+			setUnreachableCondition(oktaClient, unreachable)
+			setStatusConditions(oktaClient, ReasonOktaUnreachable, err)
+			reconcileOutcomes.WithLabelValues(ReasonOktaUnreachable).Inc()
+			if err = r.Status().Update(ctx, oktaClient); err != nil {
+				return ctrl.Result{}, fmt.Errorf("failed to update status of oktaClient %q: %w", req.NamespacedName, err)
+			}
+			return ctrl.Result{RequeueAfter: earliest(r.ResyncInterval, r.Probe.interval)}, nil
+		}
+	}
+
+	// Okta is only queried once per reconciliation, even though drift detection and the sync below look up the same
+	// resources.
+	oktaAPI = newLookups(oktaAPI)
+
+	// Changes made in Okta after the spec has been synced are drift. It is corrected below, unless it is only reported.
+	var drift []string
+	if isSynced(oktaClient) {
+		drift, err = detectDrift(oktaClient, ctx, oktaAPI)
+		if err != nil {
+			err = fmt.Errorf("failed to detect drift of oktaClient %q: %w", req.NamespacedName, err)
+			return r.failed(oktaClient, ctx, ReasonDriftDetectionFailed, err)
+		}
+	}
+	if len(drift) > 0 {
+		driftDetections.WithLabelValues(string(driftPolicy(oktaClient))).Inc()
+	}
+	reportDrift := len(drift) > 0 && driftPolicy(oktaClient) == oktav1alpha1.DriftPolicyReport
+
+	// Reported drift of the trusted origins is left alone. A synced spec has no removed trusted origins to prune.
+	if !reportDrift {
+		err = updateTrustedOrigins(oktaClient, ctx, r.Client, oktaAPI)
+		if err != nil {
+			err = fmt.Errorf("failed to create or update the trusted origins %q: %w", req.NamespacedName, err)
+			return r.failed(oktaClient, ctx, ReasonTrustedOriginsFailed, err)
+		}
+	}
+
+	lastSecretRotationRequest := oktaClient.Status.LastSecretRotationRequest
+	err = updateApplication(oktaClient, ctx, req, r.Client, oktaAPI)
 	if err != nil {
-		return ctrl.Result{}, fmt.Errorf("failed to create or update the trusted origins %q: %w", req.NamespacedName, err)
+		err = fmt.Errorf("failed to create or update application %q: %w", req.NamespacedName, err)
+		return r.failed(oktaClient, ctx, ReasonApplicationFailed, err)
+	}
+
+	if oktaClient.Status.LastSecretRotationRequest != lastSecretRotationRequest {
+		r.Recorder.Eventf(oktaClient, core.EventTypeNormal, EventReasonSecretRotated, "Rotated client secret as requested by annotation %s=%s", annotationRotateSecret, oktaClient.Status.LastSecretRotationRequest)
 	}
 
-	err = updateApplication(oktaClient, ctx, req, r.Client)
+	setUnreachableCondition(oktaClient, nil)
+	if reportDrift {
+		err = r.reportDrift(oktaClient, ctx, drift)
+	} else {
+		if len(drift) > 0 {
+			r.Recorder.Eventf(oktaClient, core.EventTypeNormal, EventReasonDriftCorrected, "Corrected drift of the Okta application: %s", strings.Join(drift, "; "))
+		}
+		setDriftCondition(oktaClient, drift)
+		oktaClient.Status.SyncedGeneration = oktaClient.Generation
+		err = r.updateStatus(oktaClient, ctx, ReasonSynced, nil)
+	}
 	if err != nil {
-		return ctrl.Result{}, fmt.Errorf("failed to create or update application %q: %w", req.NamespacedName, err)
+		return ctrl.Result{}, err
+	}
+
+	now := time.Now()
+	return ctrl.Result{RequeueAfter: earliest(nextKeyRotation(oktaClient, now), nextSecretRotation(oktaClient, now), r.ResyncInterval)}, nil
+}
+
+// failed records the reconciliation error in the status of the OktaClient. If the error is caused by an exhausted rate
+// limit of the Okta API, the OktaClient is reconciled again, once the rate limit resets. Otherwise, the error is
+// returned, so the OktaClient is retried with the backoff of the controller.
+func (r *OktaClientReconciler) failed(oktaClient *oktav1alpha1.OktaClient, ctx context.Context, reason string, reconcileErr error) (ctrl.Result, error) {
+	if okta.IsUnreachable(reconcileErr) {
+		setUnreachableCondition(oktaClient, reconcileErr)
+		reason = ReasonOktaUnreachable
+	}
+
+	retryAfter, rateLimited := okta.RateLimitRetryAfter(reconcileErr)
+	if !rateLimited {
+		return ctrl.Result{}, r.updateStatus(oktaClient, ctx, reason, reconcileErr)
 	}
 
-	return ctrl.Result{}, nil
+	ctrllog.FromContext(ctx).Info("Okta API rate limit exceeded, retrying later", "retryAfter", retryAfter)
+	_ = r.updateStatus(oktaClient, ctx, ReasonRateLimited, reconcileErr)
+	return ctrl.Result{RequeueAfter: retryAfter}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *OktaClientReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	err := mgr.GetFieldIndexer().IndexField(context.Background(), &oktav1alpha1.OktaClient{}, trustedOriginsIndex, indexTrustedOrigins)
+	if err != nil {
+		return fmt.Errorf("failed to index oktaClients by %q: %w", trustedOriginsIndex, err)
+	}
+	err = mgr.GetFieldIndexer().IndexField(context.Background(), &oktav1alpha1.OktaClient{}, applicationIDIndex, indexApplicationID)
+	if err != nil {
+		return fmt.Errorf("failed to index oktaClients by %q: %w", applicationIDIndex, err)
+	}
+
+	controller := ctrl.NewControllerManagedBy(mgr).
 		For(&oktav1alpha1.OktaClient{}).
 		Owns(&core.Secret{}).
-		Named("oktaClient").
-		Complete(r)
+		Named("oktaClient")
+	if r.Events != nil {
+		controller = controller.WatchesRawSource(&source.Channel{Source: r.Events}, &handler.EnqueueRequestForObject{})
+	}
+	if r.Probe != nil {
+		controller = controller.WatchesRawSource(&source.Channel{Source: r.Probe.Events()}, &handler.EnqueueRequestForObject{})
+	}
+	return controller.Complete(r)
 }
 
 func (r *OktaClientReconciler) cleanUp(oktaClient *oktav1alpha1.OktaClient, ctx context.Context, req ctrl.Request) error {
+	policy := r.deletionPolicy(oktaClient)
+
+	// Orphaned applications are left untouched. Their Okta organization is not needed, so it might be gone already.
+	var oktaAPI okta.API
+	if policy != oktav1alpha1.DeletionPolicyOrphan {
+		var err error
+		oktaAPI, err = r.oktaAPI(oktaClient, ctx)
+		if err != nil {
+			return fmt.Errorf("failed to connect to the Okta organization of oktaClient %q: %w", req.NamespacedName, err)
+		}
+	}
+
 	// Delete App
-	err := deleteApplication(oktaClient, ctx)
+	err := deleteApplication(oktaClient, ctx, policy, oktaAPI)
 	if err != nil {
 		return err
 	}
 
 	// Delete trusted origins
-	err = deleteTrustedOrigins(oktaClient, ctx)
+	err = deleteTrustedOrigins(oktaClient, ctx, r.Client, policy, oktaAPI)
+	if err != nil {
+		return err
+	}
+
+	// Delete or retain the secret
+	err = cleanUpSecret(oktaClient, ctx, req, r.Client)
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+// deletionPolicy returns the deletion policy of the OktaClient or the default deletion policy, if it has none. Adopted
+// applications have not been created by the operator, so they are orphaned by default.
+func (r *OktaClientReconciler) deletionPolicy(oktaClient *oktav1alpha1.OktaClient) oktav1alpha1.DeletionPolicy {
+	if oktaClient.Spec.DeletionPolicy != "" {
+		return oktaClient.Spec.DeletionPolicy
+	}
+	if oktaClient.Spec.ExistingApplicationID != "" {
+		return oktav1alpha1.DeletionPolicyOrphan
+	}
+	if r.DefaultDeletionPolicy != "" {
+		return r.DefaultDeletionPolicy
+	}
+	return oktav1alpha1.DeletionPolicyDelete
+}
+
+// earliest returns the shortest positive duration or zero, if there is none.
+func earliest(durations ...time.Duration) time.Duration {
+	var result time.Duration
+	for _, d := range durations {
+		if d > 0 && (result == 0 || d < result) {
+			result = d
+		}
+	}
+	return result
+}
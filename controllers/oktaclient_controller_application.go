@@ -13,80 +13,179 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"time"
 )
 
-var (
-	getAppByLabel         = okta.GetApplicationByLabel
-	createApp             = okta.CreateApplication
-	deleteApp             = okta.DeleteApplication
-	newSecret             = okta.NewSecret
-	createGroupAssignment = okta.CreateApplicationGroupAssignment
-	createOrUpdateSecret  = controllerutil.CreateOrUpdate
-	getSecret             = getSecretImpl
-)
-
-func updateApplication(oktaClient *oktav1alpha1.OktaClient, ctx context.Context, req ctrl.Request, kubernetesClient client.Client) error {
+func updateApplication(oktaClient *oktav1alpha1.OktaClient, ctx context.Context, req ctrl.Request, kubernetesClient client.Client, oktaAPI okta.API) error {
 	// Update application
 	log := ctrllog.FromContext(ctx)
 	secretName := oktaClient.Name
 	appName := oktaClient.Spec.Name
-	clientUri := oktaClient.Spec.ClientUri
-	redirectUris := oktaClient.Spec.RedirectUris
-	postLogoutRedirectUris := oktaClient.Spec.PostLogoutRedirectUris
+	settings := applicationSettings(oktaClient)
 	groupId := oktaClient.Spec.GroupId
 
-	app, err := getAppByLabel(appName)
+	// Once the spec has been synced, differences to the Okta application are drift. The Report drift policy leaves them
+	// to be resolved in Okta, but client secrets and keys are still rotated.
+	reportDrift := isSynced(oktaClient) && driftPolicy(oktaClient) == oktav1alpha1.DriftPolicyReport
+
+	app, err := getApplication(oktaClient, ctx, oktaAPI)
 	log.Info("Queried application", "application", appName, "exists", app != nil)
 	if err != nil {
 		return fmt.Errorf("failed to get application %q: %w", appName, err)
 	}
+	if app == nil && reportDrift {
+		return nil
+	}
+	if app == nil && oktaClient.Spec.ExistingApplicationID != "" {
+		return fmt.Errorf("existing application %q does not exist", oktaClient.Spec.ExistingApplicationID)
+	}
+
+	// Check if we have the client credentials for the application.
+	secret, err := getSecret(kubernetesClient, ctx, req, secretName)
+	if errors.IsNotFound(err) {
+		secret = nil
+	} else if err != nil {
+		return fmt.Errorf("failed to get secret %q for application %q: %w", secretName, appName, err)
+	}
+
+	var privateKey []byte
+	var keyStatus *oktav1alpha1.PrivateKeyStatus
+	if settings.TokenEndpointAuthMethod == "private_key_jwt" {
+		settings.Keys, privateKey, keyStatus, err = updateKeys(oktaClient, secret, app, time.Now())
+		if err != nil {
+			return fmt.Errorf("failed to update keys for application %q: %w", appName, err)
+		}
+		if privateKey != nil {
+			log.Info("Generated application key pair", "application", appName, "keyId", keyStatus.KeyID)
+		}
+	}
 
 	if app == nil {
 		log.Info("Creating application", "application", appName)
-		app, err = createApp(appName, clientUri, redirectUris, postLogoutRedirectUris)
+		app, err = oktaAPI.CreateApplication(ctx, settings)
 		if err != nil {
 			return fmt.Errorf("failed to create application %q: %w", appName, err)
 		}
 	} else {
-		// The application has already been created in Okta. Check if we have the client credentials for the application.
-		err := getSecret(kubernetesClient, ctx, req, secretName)
-		if err != nil {
-			if errors.IsNotFound(err) {
-				// The secret does not exist, and we do not have the credentials at hand. Create a new secret.
-				log.Info("Rotating application secret")
-				clientSecret, err := newSecret(app.ClientID)
-				if err != nil {
-					return fmt.Errorf("could not rotate secret for application %q: %w", appName, err)
-				}
-				app.ClientSecret = clientSecret
+		// The application has already been created in Okta. Make sure its settings match the spec.
+		update := settings
+		if reportDrift {
+			update = app.ApplicationSettings
+			if settings.TokenEndpointAuthMethod == "private_key_jwt" {
+				update.Keys = settings.Keys
 			}
 		}
+		if !applicationMatches(app, update) {
+			log.Info("Updating application", "application", appName)
+			err = oktaAPI.UpdateApplication(ctx, app, update)
+			if err != nil {
+				return fmt.Errorf("failed to update application %q: %w", appName, err)
+			}
+		}
+
+		// Applications without a client secret (e.g. public clients) do not need one. Applications switching to a client
+		// authentication method with a client secret have none in their secret yet.
+		adopted := oktaClient.Spec.ExistingApplicationID != ""
+		if hasClientSecret(settings) && len(secretCredential(secret, secretKeyClientSecret)) == 0 {
+			// Replacing the client secrets of an adopted application breaks its existing workloads.
+			if adopted && !oktaClient.Spec.AllowNewSecret {
+				return fmt.Errorf("client secret of adopted application %q is unknown; store it in secret %q or allow a new secret", appName, secretName)
+			}
+
+			// The secret does not exist, and we do not have the credentials at hand. Create a new secret.
+			log.Info("Rotating application secret")
+			clientSecret, err := oktaAPI.NewSecret(ctx, app.ClientID)
+			if err != nil {
+				return fmt.Errorf("could not rotate secret for application %q: %w", appName, err)
+			}
+			app.ClientSecret = clientSecret
+
+			// The new secret replaced all client secrets of the application. Start over with scheduled rotations.
+			oktaClient.Status.SecretRotation = nil
+		}
 	}
 
-	// If we have a new ClientSecret, create or update the K8s secret
-	if app.ClientSecret != "" {
-		secret := &core.Secret{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      secretName,
-				Namespace: req.Namespace,
-			},
+	var rotation *oktav1alpha1.SecretRotationStatus
+	requested := secretRotationRequested(oktaClient)
+	if hasClientSecret(settings) {
+		rotate := rotateClientSecret
+		if requested != "" {
+			rotate = rotateClientSecretOnDemand
 		}
-		_, err = createOrUpdateSecret(ctx, kubernetesClient, secret, func() error {
-			secret.StringData = map[string]string{
-				"OKTA_CLIENT_ID":     app.ClientID,
-				"OKTA_CLIENT_SECRET": app.ClientSecret,
-			}
 
-			return nil
-		})
+		clientSecret, rotated, err := rotate(oktaClient, ctx, app, time.Now(), oktaAPI)
+		if err != nil {
+			return fmt.Errorf("could not rotate secret for application %q: %w", appName, err)
+		}
+		if clientSecret != nil {
+			log.Info("Rotated application secret", "application", appName, "secretId", clientSecret.ID)
+			app.ClientSecret = clientSecret.Secret
+			rotation = rotated
+		}
+	}
+
+	metadata, err := oktaAPI.GetAuthorizationServerMetadata(ctx, oktaClient.Spec.AuthorizationServerID)
+	if err != nil {
+		return fmt.Errorf("failed to get issuer for application %q: %w", appName, err)
+	}
+
+	// A secret handing in the credentials of an adopted application belongs to the user. It is updated, but neither
+	// owned nor deleted by the operator.
+	userProvided := oktaClient.Spec.ExistingApplicationID != "" && secret != nil && !metav1.IsControlledBy(secret, oktaClient)
+
+	// Create or update the K8s secret. It is only updated, if new credentials have been created or its template changed.
+	secret = &core.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secretName,
+			Namespace: req.Namespace,
+		},
 	}
+	_, err = controllerutil.CreateOrUpdate(ctx, kubernetesClient, secret, func() error {
+		values := newSecretValues(oktaClient, app, oktaAPI.OrgURL(), metadata, secret, privateKey)
+		if keyStatus != nil {
+			values.KeyID = keyStatus.KeyID
+		}
+		err := renderSecret(oktaClient, secret, values)
+		if err != nil || userProvided {
+			return err
+		}
+
+		return controllerutil.SetControllerReference(oktaClient, secret, kubernetesClient.Scheme())
+	})
 	if err != nil {
 		return fmt.Errorf("failed to create / update secret for application %q: %w", appName, err)
 	}
 
-	if groupId != "" {
+	// Only record the key pairs, once the new private key is stored. Otherwise, the status refers to a key the secret
+	// does not contain.
+	if keyStatus != nil {
+		oktaClient.Status.PrivateKey = keyStatus
+	}
+
+	// Only record a rotation, once the new client secret is stored. Otherwise, it is rotated again.
+	if rotation != nil {
+		oktaClient.Status.SecretRotation = rotation
+		trigger := "scheduled"
+		if requested != "" {
+			trigger = "requested"
+		}
+		secretRotations.WithLabelValues(trigger).Inc()
+	}
+	if requested != "" && (rotation != nil || !hasClientSecret(settings)) {
+		oktaClient.Status.LastSecretRotationRequest = requested
+	}
+
+	oktaClient.Status.ApplicationID = app.ID
+	oktaClient.Status.ClientID = app.ClientID
+	if oktaClient.Spec.ExistingApplicationID != "" {
+		oktaClient.Status.Adopted = true
+	}
+	oktaClient.Status.SecretName = secretName
+	oktaClient.Status.Issuer = metadata.Issuer
+
+	if groupId != "" && !reportDrift {
 		log.Info("Creating application/group assignment", "application", appName, "groupId", groupId)
-		err = createGroupAssignment(app, groupId)
+		err = oktaAPI.CreateApplicationGroupAssignment(ctx, app, groupId)
 		if err != nil {
 			return fmt.Errorf("failed to add application %q to group %q: %w", appName, groupId, err)
 		}
@@ -95,23 +194,89 @@ func updateApplication(oktaClient *oktav1alpha1.OktaClient, ctx context.Context,
 	return nil
 }
 
-func getSecretImpl(k8sClient client.Client, ctx context.Context, req ctrl.Request, secretName string) error {
-	return k8sClient.Get(ctx, types.NamespacedName{Namespace: req.Namespace, Name: secretName}, &core.Secret{})
+// getApplication returns the Okta application of the OktaClient or nil, if it does not exist. An existing application
+// configured in the spec is always looked up by its ID. Once the application ID has been recorded in the status, the
+// application is looked up by ID. Otherwise, the application with exactly the configured label is adopted.
+func getApplication(oktaClient *oktav1alpha1.OktaClient, ctx context.Context, oktaAPI okta.API) (*okta.Application, error) {
+	if oktaClient.Spec.ExistingApplicationID != "" {
+		return oktaAPI.GetApplicationByID(ctx, oktaClient.Spec.ExistingApplicationID)
+	}
+	if oktaClient.Status.ApplicationID != "" {
+		return oktaAPI.GetApplicationByID(ctx, oktaClient.Status.ApplicationID)
+	}
+	return oktaAPI.GetApplicationByLabel(ctx, oktaClient.Spec.Name)
+}
+
+func getSecret(k8sClient client.Client, ctx context.Context, req ctrl.Request, secretName string) (*core.Secret, error) {
+	secret := &core.Secret{}
+	err := k8sClient.Get(ctx, types.NamespacedName{Namespace: req.Namespace, Name: secretName}, secret)
+	return secret, err
 }
 
-func deleteApplication(oktaClient *oktav1alpha1.OktaClient, ctx context.Context) error {
+// cleanUpSecret deletes the secret of a deleted OktaClient or removes its owner reference, depending on the secret
+// deletion policy. Retained secrets are not garbage collected by Kubernetes.
+func cleanUpSecret(oktaClient *oktav1alpha1.OktaClient, ctx context.Context, req ctrl.Request, kubernetesClient client.Client) error {
+	log := ctrllog.FromContext(ctx)
+	secretName := oktaClient.Name
+
+	secret, err := getSecret(kubernetesClient, ctx, req, secretName)
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to get secret %q: %w", secretName, err)
+	}
+
+	if !metav1.IsControlledBy(secret, oktaClient) {
+		return nil
+	}
+
+	if oktaClient.Spec.SecretDeletionPolicy == oktav1alpha1.SecretDeletionPolicyDelete {
+		log.Info("Deleting secret", "secret", secretName)
+		err = kubernetesClient.Delete(ctx, secret)
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete secret %q: %w", secretName, err)
+		}
+		return nil
+	}
+
+	log.Info("Retaining secret", "secret", secretName)
+	err = controllerutil.RemoveControllerReference(oktaClient, secret, kubernetesClient.Scheme())
+	if err != nil {
+		return fmt.Errorf("failed to remove owner reference from secret %q: %w", secretName, err)
+	}
+	err = kubernetesClient.Update(ctx, secret)
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to update secret %q: %w", secretName, err)
+	}
+	return nil
+}
+
+// deleteApplication deletes or deactivates the Okta application of an OktaClient, depending on the deletion policy.
+// Orphaned applications are left untouched.
+func deleteApplication(oktaClient *oktav1alpha1.OktaClient, ctx context.Context, policy oktav1alpha1.DeletionPolicy, oktaAPI okta.API) error {
 	log := ctrllog.FromContext(ctx)
 	appName := oktaClient.Spec.Name
-	app, err := getAppByLabel(appName)
+	if policy == oktav1alpha1.DeletionPolicyOrphan {
+		log.Info("Orphaning application", "appName", appName)
+		return nil
+	}
+
+	app, err := getApplication(oktaClient, ctx, oktaAPI)
 
 	log.Info("Queried application", "appName", appName, "exists", app != nil)
 	if err != nil {
 		return fmt.Errorf("failed to get application %q: %w", appName, err)
 	}
 
-	if app != nil {
+	if app != nil && policy == oktav1alpha1.DeletionPolicyDeactivate {
+		log.Info("Deactivating application", "appName", appName)
+		err = oktaAPI.DeactivateApplication(ctx, app)
+		if err != nil {
+			return fmt.Errorf("failed to deactivate application %q: %w", appName, err)
+		}
+	} else if app != nil {
 		log.Info("Deleting application", "appName", appName)
-		err = deleteApp(app)
+		err = oktaAPI.DeleteApplication(ctx, app)
 		if err != nil {
 			return fmt.Errorf("failed to delete application %q: %w", appName, err)
 		}
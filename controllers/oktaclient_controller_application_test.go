@@ -1,53 +1,395 @@
 package controllers
 
 import (
+	"context"
+	"github.com/jaconi-io/okta-operator/api/v1alpha1"
+	"github.com/jaconi-io/okta-operator/okta"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"testing"
 )
 
 func TestUpdateApplicationNotExists(t *testing.T) {
-	resetToLocal()
+	t.Parallel()
+	oktaAPI := newOktaMock()
+	oktaClient := testAppClient.DeepCopy()
 
-	err := updateApplication(&testAppClient, nil, testRequest, nil)
+	err := updateApplication(oktaClient, context.Background(), testRequest, newTestClient(), oktaAPI)
 	if err != nil {
 		t.Errorf("error updating application")
 	}
-	if len(testOktaClients) != 1 {
-		t.Errorf("got %d applications, wanted %d", len(testOktaClients), 1)
+	if len(oktaAPI.applications) != 1 {
+		t.Errorf("got %d applications, wanted %d", len(oktaAPI.applications), 1)
 	}
-	if appsCreated != 1 {
-		t.Errorf("got %d method calls, wanted %d", appsCreated, 1)
+	if oktaAPI.appsCreated != 1 {
+		t.Errorf("got %d method calls, wanted %d", oktaAPI.appsCreated, 1)
+	}
+	if oktaClient.Status.ApplicationID != testApp.ID || oktaClient.Status.ClientID != testApp.ClientID {
+		t.Errorf("got status %+v, wanted application %q and client %q", oktaClient.Status, testApp.ID, testApp.ClientID)
 	}
 }
 
 func TestUpdateApplicationExists(t *testing.T) {
-	resetToLocal()
-	_, _ = addTestApplication(testAppClient.Spec.Name, "", nil, nil)
+	t.Parallel()
+	oktaAPI := newOktaMock()
+	oktaAPI.addApplication(applicationSettings(&testAppClient))
 
-	err := updateApplication(&testAppClient, nil, testRequest, nil)
+	err := updateApplication(testAppClient.DeepCopy(), context.Background(), testRequest, newTestClient(newTestSecret(nil)), oktaAPI)
 	if err != nil {
 		t.Errorf("error updating application")
 	}
-	if len(testOktaClients) != 1 {
-		t.Errorf("got %d applications, wanted %d", len(testOktaClients), 1)
+	if len(oktaAPI.applications) != 1 {
+		t.Errorf("got %d applications, wanted %d", len(oktaAPI.applications), 1)
 	}
-	if appsCreated != 0 {
-		t.Errorf("got %d method calls, wanted %d", appsCreated, 0)
+	if oktaAPI.appsCreated != 0 {
+		t.Errorf("got %d method calls, wanted %d", oktaAPI.appsCreated, 0)
+	}
+}
+
+func TestUpdateApplicationSwitchToClientSecret(t *testing.T) {
+	t.Parallel()
+	oktaClient := testAppClient.DeepCopy()
+	oktaClient.Spec.TokenEndpointAuthMethod = "private_key_jwt"
+	oktaAPI := newOktaMock()
+	app := oktaAPI.addApplication(applicationSettings(oktaClient))
+	app.ClientSecret = ""
+	kubernetesClient := newTestClient()
+
+	err := updateApplication(oktaClient, context.Background(), testRequest, kubernetesClient, oktaAPI)
+	if err != nil {
+		t.Fatalf("error updating application: %v", err)
+	}
+
+	// The secret exists, but has no client secret yet.
+	oktaClient.Spec.TokenEndpointAuthMethod = "client_secret_basic"
+	err = updateApplication(oktaClient, context.Background(), testRequest, kubernetesClient, oktaAPI)
+	if err != nil {
+		t.Fatalf("error updating application: %v", err)
+	}
+	secret, _ := getSecret(kubernetesClient, context.Background(), testRequest, oktaClient.Name)
+	if oktaAPI.secretsRotated != 1 || string(secret.Data[secretKeyClientSecret]) != "secret" {
+		t.Errorf("got %d rotations and client secret %q, wanted a new client secret", oktaAPI.secretsRotated, secret.Data[secretKeyClientSecret])
 	}
 }
 
 func TestDeleteApplication(t *testing.T) {
-	resetToLocal()
-	_, _ = addTestApplication(testAppClient.Spec.Name, "", nil, nil)
+	t.Parallel()
+	oktaAPI := newOktaMock()
+	oktaAPI.addApplication(applicationSettings(&testAppClient))
+
+	err := deleteApplication(testAppClient.DeepCopy(), context.Background(), v1alpha1.DeletionPolicyDelete, oktaAPI)
+	if err != nil {
+		t.Errorf("error deleting application")
+	}
+
+	if len(oktaAPI.applications) != 0 {
+		t.Errorf("got %d applications, wanted %d", len(oktaAPI.applications), 0)
+	}
+	if oktaAPI.appsDeleted != 1 {
+		t.Errorf("got %d method calls, wanted %d", oktaAPI.appsDeleted, 1)
+	}
+}
+
+func TestDeleteApplicationDeactivate(t *testing.T) {
+	t.Parallel()
+	oktaAPI := newOktaMock()
+	oktaAPI.addApplication(applicationSettings(&testAppClient))
+
+	err := deleteApplication(testAppClient.DeepCopy(), context.Background(), v1alpha1.DeletionPolicyDeactivate, oktaAPI)
+	if err != nil {
+		t.Errorf("error deactivating application")
+	}
+
+	if len(oktaAPI.applications) != 1 {
+		t.Errorf("got %d applications, wanted %d", len(oktaAPI.applications), 1)
+	}
+	if oktaAPI.appsDeactivated != 1 || oktaAPI.appsDeleted != 0 {
+		t.Errorf("got %d deactivations and %d deletions, wanted %d and %d", oktaAPI.appsDeactivated, oktaAPI.appsDeleted, 1, 0)
+	}
+}
+
+func TestDeleteApplicationOrphan(t *testing.T) {
+	t.Parallel()
+	oktaAPI := newOktaMock()
+	oktaAPI.addApplication(applicationSettings(&testAppClient))
+
+	err := deleteApplication(testAppClient.DeepCopy(), context.Background(), v1alpha1.DeletionPolicyOrphan, oktaAPI)
+	if err != nil {
+		t.Errorf("error orphaning application")
+	}
+
+	if len(oktaAPI.applications) != 1 || oktaAPI.appsDeactivated != 0 || oktaAPI.appsDeleted != 0 {
+		t.Errorf("got %d deactivations and %d deletions, wanted none", oktaAPI.appsDeactivated, oktaAPI.appsDeleted)
+	}
+}
+
+func TestUpdateApplicationChanged(t *testing.T) {
+	t.Parallel()
+	oktaAPI := newOktaMock()
+	oktaAPI.addApplication(applicationSettings(&testAppClient))
 
-	err := deleteApplication(&testAppClient, nil)
+	oktaClient := testAppClient.DeepCopy()
+	oktaClient.Spec.ClientUri = "https://example.com"
+	oktaClient.Spec.RedirectUris = []string{"https://example.com/callback"}
+
+	err := updateApplication(oktaClient, context.Background(), testRequest, newTestClient(newTestSecret(nil)), oktaAPI)
+	if err != nil {
+		t.Errorf("error updating application")
+	}
+	if oktaAPI.appsUpdated != 1 {
+		t.Errorf("got %d method calls, wanted %d", oktaAPI.appsUpdated, 1)
+	}
+	app := oktaAPI.applications[testAppClient.Spec.Name]
+	if app.ClientUri != "https://example.com" || len(app.RedirectUris) != 1 {
+		t.Errorf("got application %+v, wanted updated settings", app)
+	}
+}
+
+func TestUpdateApplicationUnchanged(t *testing.T) {
+	t.Parallel()
+	oktaAPI := newOktaMock()
+	oktaAPI.addApplication(applicationSettings(&testAppClient))
+
+	err := updateApplication(testAppClient.DeepCopy(), context.Background(), testRequest, newTestClient(newTestSecret(nil)), oktaAPI)
+	if err != nil {
+		t.Errorf("error updating application")
+	}
+	if oktaAPI.appsUpdated != 0 {
+		t.Errorf("got %d method calls, wanted %d", oktaAPI.appsUpdated, 0)
+	}
+}
+
+func TestUpdateApplicationTrackedByID(t *testing.T) {
+	t.Parallel()
+	oktaAPI := newOktaMock()
+	oktaAPI.addApplication(okta.ApplicationSettings{Label: "renamed-client"})
+
+	oktaClient := testAppClient.DeepCopy()
+	oktaClient.Status.ApplicationID = "renamed-client"
+
+	err := updateApplication(oktaClient, context.Background(), testRequest, newTestClient(newTestSecret(nil)), oktaAPI)
+	if err != nil {
+		t.Errorf("error updating application")
+	}
+	if oktaAPI.appsCreated != 0 {
+		t.Errorf("got %d method calls, wanted %d", oktaAPI.appsCreated, 0)
+	}
+	if oktaAPI.appsUpdated != 1 {
+		t.Errorf("got %d method calls, wanted %d", oktaAPI.appsUpdated, 1)
+	}
+	if label := oktaAPI.applications["renamed-client"].Label; label != testAppClient.Spec.Name {
+		t.Errorf("got label %q, wanted %q", label, testAppClient.Spec.Name)
+	}
+}
+
+func TestDeleteApplicationTrackedByIDNotExists(t *testing.T) {
+	t.Parallel()
+	oktaAPI := newOktaMock()
+	oktaAPI.addApplication(applicationSettings(&testAppClient))
+
+	oktaClient := testAppClient.DeepCopy()
+	oktaClient.Status.ApplicationID = "unknown"
+
+	err := deleteApplication(oktaClient, context.Background(), v1alpha1.DeletionPolicyDelete, oktaAPI)
 	if err != nil {
 		t.Errorf("error deleting application")
 	}
+	if oktaAPI.appsDeleted != 0 {
+		t.Errorf("got %d method calls, wanted %d", oktaAPI.appsDeleted, 0)
+	}
+}
+
+func TestUpdateApplicationOwnsSecret(t *testing.T) {
+	t.Parallel()
+	oktaClient := testAppClient.DeepCopy()
+	kubernetesClient := newTestClient()
+
+	err := updateApplication(oktaClient, context.Background(), testRequest, kubernetesClient, newOktaMock())
+	if err != nil {
+		t.Errorf("error updating application: %v", err)
+	}
+
+	secret, err := getSecret(kubernetesClient, context.Background(), testRequest, oktaClient.Name)
+	if err != nil {
+		t.Fatalf("error getting secret: %v", err)
+	}
+	if !metav1.IsControlledBy(secret, oktaClient) {
+		t.Errorf("got owner references %v, wanted the OktaClient", secret.OwnerReferences)
+	}
+	if string(secret.Data["OKTA_CLIENT_SECRET"]) != testApp.ClientSecret {
+		t.Errorf("got client secret %q, wanted %q", secret.Data["OKTA_CLIENT_SECRET"], testApp.ClientSecret)
+	}
+}
+
+func TestCleanUpSecretNotOwned(t *testing.T) {
+	t.Parallel()
+	oktaClient := testAppClient.DeepCopy()
+	oktaClient.Spec.SecretDeletionPolicy = v1alpha1.SecretDeletionPolicyDelete
+	kubernetesClient := newTestClient(newTestSecret(nil))
+
+	// The secret is not controlled by the OktaClient and must not be touched.
+	err := cleanUpSecret(oktaClient, context.Background(), testRequest, kubernetesClient)
+	if err != nil {
+		t.Errorf("error cleaning up secret: %v", err)
+	}
+	_, err = getSecret(kubernetesClient, context.Background(), testRequest, oktaClient.Name)
+	if err != nil {
+		t.Errorf("error getting secret: %v", err)
+	}
+
+	err = cleanUpSecret(oktaClient, context.Background(), testRequest, newTestClient())
+	if err != nil {
+		t.Errorf("error cleaning up missing secret: %v", err)
+	}
+}
+
+func TestCleanUpSecretDelete(t *testing.T) {
+	t.Parallel()
+	oktaClient := testAppClient.DeepCopy()
+	oktaClient.Spec.SecretDeletionPolicy = v1alpha1.SecretDeletionPolicyDelete
+	kubernetesClient := newTestClient()
+
+	err := updateApplication(oktaClient, context.Background(), testRequest, kubernetesClient, newOktaMock())
+	if err != nil {
+		t.Errorf("error updating application: %v", err)
+	}
+
+	err = cleanUpSecret(oktaClient, context.Background(), testRequest, kubernetesClient)
+	if err != nil {
+		t.Errorf("error cleaning up secret: %v", err)
+	}
+	_, err = getSecret(kubernetesClient, context.Background(), testRequest, oktaClient.Name)
+	if !errors.IsNotFound(err) {
+		t.Errorf("got error %v, wanted the secret to be deleted", err)
+	}
+}
+
+func TestUpdateApplicationAdopt(t *testing.T) {
+	t.Parallel()
+	oktaAPI := newOktaMock()
+	oktaAPI.addApplication(okta.ApplicationSettings{Label: "hand-made"})
+	oktaClient := testAppClient.DeepCopy()
+	oktaClient.Spec.ExistingApplicationID = "hand-made"
+	secret := newTestSecret(map[string][]byte{"OKTA_CLIENT_SECRET": []byte("secret")})
+
+	err := updateApplication(oktaClient, context.Background(), testRequest, newTestClient(secret), oktaAPI)
+	if err != nil {
+		t.Errorf("error updating application: %v", err)
+	}
+	if oktaAPI.appsCreated != 0 || oktaAPI.appsUpdated != 1 {
+		t.Errorf("got %d creations and %d updates, wanted %d and %d", oktaAPI.appsCreated, oktaAPI.appsUpdated, 0, 1)
+	}
+	if oktaAPI.applications["hand-made"].Label != "test-client" {
+		t.Errorf("got label %q, wanted %q", oktaAPI.applications["hand-made"].Label, "test-client")
+	}
+	if !oktaClient.Status.Adopted || oktaClient.Status.ApplicationID != "hand-made" {
+		t.Errorf("got status %+v, wanted adopted application %q", oktaClient.Status, "hand-made")
+	}
+}
+
+func TestUpdateApplicationAdoptUserSecret(t *testing.T) {
+	t.Parallel()
+	oktaAPI := newOktaMock()
+	oktaAPI.addApplication(okta.ApplicationSettings{Label: "hand-made"})
+	oktaClient := testAppClient.DeepCopy()
+	oktaClient.Spec.ExistingApplicationID = "hand-made"
+	oktaClient.Spec.SecretDeletionPolicy = v1alpha1.SecretDeletionPolicyDelete
+	kubernetesClient := newTestClient(newTestSecret(map[string][]byte{"OKTA_CLIENT_SECRET": []byte("secret")}))
+
+	err := updateApplication(oktaClient, context.Background(), testRequest, kubernetesClient, oktaAPI)
+	if err != nil {
+		t.Fatalf("error updating application: %v", err)
+	}
+
+	// The secret handing in the client secret belongs to the user.
+	secret, err := getSecret(kubernetesClient, context.Background(), testRequest, oktaClient.Name)
+	if err != nil {
+		t.Fatalf("error getting secret: %v", err)
+	}
+	if len(secret.OwnerReferences) != 0 || string(secret.Data["OKTA_CLIENT_ID"]) != testApp.ClientID {
+		t.Errorf("got owner references %v and data %q, wanted an updated secret without owner", secret.OwnerReferences, secret.Data)
+	}
+
+	err = cleanUpSecret(oktaClient, context.Background(), testRequest, kubernetesClient)
+	if err != nil {
+		t.Errorf("error cleaning up secret: %v", err)
+	}
+	_, err = getSecret(kubernetesClient, context.Background(), testRequest, oktaClient.Name)
+	if err != nil {
+		t.Errorf("error getting secret: %v", err)
+	}
+}
+
+func TestDeleteApplicationAdoptNotExists(t *testing.T) {
+	t.Parallel()
+	oktaAPI := newOktaMock()
+	oktaClient := testAppClient.DeepCopy()
+	oktaClient.Spec.ExistingApplicationID = "hand-made"
+	oktaClient.Status.ApplicationID = "hand-made"
+
+	// The adopted application has been deleted in Okta already.
+	err := deleteApplication(oktaClient, context.Background(), v1alpha1.DeletionPolicyDelete, oktaAPI)
+	if err != nil {
+		t.Errorf("error deleting application: %v", err)
+	}
+
+	drift, err := detectDrift(oktaClient, context.Background(), oktaAPI)
+	if err != nil || len(drift) != 1 || drift[0] != `application "hand-made" is missing` {
+		t.Errorf("got drift %q and error %v, wanted the missing application", drift, err)
+	}
+}
 
-	if len(testOktaClients) != 0 {
-		t.Errorf("got %d applications, wanted %d", len(testOktaClients), 0)
+func TestDeletionPolicyAdopt(t *testing.T) {
+	t.Parallel()
+	r := &OktaClientReconciler{DefaultDeletionPolicy: v1alpha1.DeletionPolicyDeactivate}
+	oktaClient := testAppClient.DeepCopy()
+	if policy := r.deletionPolicy(oktaClient); policy != v1alpha1.DeletionPolicyDeactivate {
+		t.Errorf("got deletion policy %q, wanted %q", policy, v1alpha1.DeletionPolicyDeactivate)
 	}
-	if appsDeleted != 1 {
-		t.Errorf("got %d method calls, wanted %d", appsDeleted, 1)
+
+	oktaClient.Spec.ExistingApplicationID = "hand-made"
+	if policy := r.deletionPolicy(oktaClient); policy != v1alpha1.DeletionPolicyOrphan {
+		t.Errorf("got deletion policy %q, wanted %q", policy, v1alpha1.DeletionPolicyOrphan)
+	}
+
+	oktaClient.Spec.DeletionPolicy = v1alpha1.DeletionPolicyDelete
+	if policy := r.deletionPolicy(oktaClient); policy != v1alpha1.DeletionPolicyDelete {
+		t.Errorf("got deletion policy %q, wanted %q", policy, v1alpha1.DeletionPolicyDelete)
+	}
+}
+
+func TestUpdateApplicationAdoptNotExists(t *testing.T) {
+	t.Parallel()
+	oktaAPI := newOktaMock()
+	oktaClient := testAppClient.DeepCopy()
+	oktaClient.Spec.ExistingApplicationID = "hand-made"
+
+	err := updateApplication(oktaClient, context.Background(), testRequest, newTestClient(), oktaAPI)
+	if err == nil {
+		t.Errorf("expected error adopting missing application")
+	}
+	if oktaAPI.appsCreated != 0 {
+		t.Errorf("got %d method calls, wanted %d", oktaAPI.appsCreated, 0)
+	}
+}
+
+func TestUpdateApplicationAdoptUnknownSecret(t *testing.T) {
+	t.Parallel()
+	oktaAPI := newOktaMock()
+	oktaAPI.addApplication(okta.ApplicationSettings{Label: "hand-made"})
+	oktaClient := testAppClient.DeepCopy()
+	oktaClient.Spec.ExistingApplicationID = "hand-made"
+	kubernetesClient := newTestClient(&core.Secret{})
+
+	err := updateApplication(oktaClient, context.Background(), testRequest, kubernetesClient, oktaAPI)
+	if err == nil {
+		t.Errorf("expected error adopting application with unknown client secret")
+	}
+
+	oktaClient.Spec.AllowNewSecret = true
+	err = updateApplication(oktaClient, context.Background(), testRequest, kubernetesClient, oktaAPI)
+	if err != nil {
+		t.Errorf("error updating application: %v", err)
 	}
 }
@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"fmt"
+	oktav1alpha1 "github.com/jaconi-io/okta-operator/api/v1alpha1"
+	"github.com/jaconi-io/okta-operator/okta"
+	"slices"
+)
+
+// applicationType describes the defaults and the supported settings of an Okta application type.
+type applicationType struct {
+	defaultGrantTypes              []string
+	defaultResponseTypes           []string
+	defaultTokenEndpointAuthMethod string
+
+	grantTypes               []string
+	responseTypes            []string
+	tokenEndpointAuthMethods []string
+	redirects                bool
+}
+
+var applicationTypes = map[oktav1alpha1.ApplicationType]applicationType{
+	oktav1alpha1.ApplicationTypeWeb: {
+		defaultGrantTypes:              []string{"refresh_token", "authorization_code"},
+		defaultResponseTypes:           []string{"code"},
+		defaultTokenEndpointAuthMethod: "client_secret_post",
+		grantTypes:                     []string{"authorization_code", "implicit", "refresh_token", "client_credentials"},
+		responseTypes:                  []string{"code", "token", "id_token"},
+		tokenEndpointAuthMethods:       []string{"client_secret_basic", "client_secret_post", "client_secret_jwt", "private_key_jwt"},
+		redirects:                      true,
+	},
+	oktav1alpha1.ApplicationTypeBrowser: {
+		defaultGrantTypes:              []string{"authorization_code"},
+		defaultResponseTypes:           []string{"code"},
+		defaultTokenEndpointAuthMethod: "none",
+		grantTypes:                     []string{"authorization_code", "implicit", "refresh_token"},
+		responseTypes:                  []string{"code", "token", "id_token"},
+		tokenEndpointAuthMethods:       []string{"none"},
+		redirects:                      true,
+	},
+	oktav1alpha1.ApplicationTypeNative: {
+		defaultGrantTypes:              []string{"refresh_token", "authorization_code"},
+		defaultResponseTypes:           []string{"code"},
+		defaultTokenEndpointAuthMethod: "none",
+		grantTypes:                     []string{"authorization_code", "implicit", "password", "refresh_token", "urn:ietf:params:oauth:grant-type:device_code"},
+		responseTypes:                  []string{"code", "token", "id_token"},
+		tokenEndpointAuthMethods:       []string{"none", "client_secret_basic", "client_secret_post", "client_secret_jwt", "private_key_jwt"},
+		redirects:                      true,
+	},
+	oktav1alpha1.ApplicationTypeService: {
+		defaultGrantTypes:              []string{"client_credentials"},
+		defaultResponseTypes:           []string{"token"},
+		defaultTokenEndpointAuthMethod: "client_secret_post",
+		grantTypes:                     []string{"client_credentials"},
+		responseTypes:                  []string{"token"},
+		tokenEndpointAuthMethods:       []string{"client_secret_basic", "client_secret_post", "client_secret_jwt", "private_key_jwt"},
+		redirects:                      false,
+	},
+}
+
+// applicationSettings returns the desired settings of the Okta application of the OktaClient. Settings not specified
+// by the OktaClient are defaulted based on the application type.
+func applicationSettings(oktaClient *oktav1alpha1.OktaClient) okta.ApplicationSettings {
+	spec := oktaClient.Spec
+	settings := okta.ApplicationSettings{
+		Label:                   spec.Name,
+		ClientUri:               spec.ClientUri,
+		RedirectUris:            spec.RedirectUris,
+		PostLogoutRedirectUris:  spec.PostLogoutRedirectUris,
+		ApplicationType:         string(spec.ApplicationType),
+		TokenEndpointAuthMethod: string(spec.TokenEndpointAuthMethod),
+	}
+	for _, grantType := range spec.GrantTypes {
+		settings.GrantTypes = append(settings.GrantTypes, string(grantType))
+	}
+	for _, responseType := range spec.ResponseTypes {
+		settings.ResponseTypes = append(settings.ResponseTypes, string(responseType))
+	}
+
+	if settings.ApplicationType == "" {
+		settings.ApplicationType = string(oktav1alpha1.ApplicationTypeWeb)
+	}
+
+	appType, ok := applicationTypes[oktav1alpha1.ApplicationType(settings.ApplicationType)]
+	if !ok {
+		return settings
+	}
+	if len(settings.GrantTypes) == 0 {
+		settings.GrantTypes = appType.defaultGrantTypes
+	}
+	if len(settings.ResponseTypes) == 0 {
+		settings.ResponseTypes = appType.defaultResponseTypes
+	}
+	if settings.TokenEndpointAuthMethod == "" {
+		settings.TokenEndpointAuthMethod = appType.defaultTokenEndpointAuthMethod
+	}
+
+	return settings
+}
+
+// validateApplicationSettings returns an error, if the settings are not supported by the application type.
+func validateApplicationSettings(settings okta.ApplicationSettings) error {
+	appType, ok := applicationTypes[oktav1alpha1.ApplicationType(settings.ApplicationType)]
+	if !ok {
+		return fmt.Errorf("unsupported application type %q", settings.ApplicationType)
+	}
+
+	for _, grantType := range settings.GrantTypes {
+		if !slices.Contains(appType.grantTypes, grantType) {
+			return fmt.Errorf("grant type %q is not supported by %q applications", grantType, settings.ApplicationType)
+		}
+	}
+
+	for _, responseType := range settings.ResponseTypes {
+		if !slices.Contains(appType.responseTypes, responseType) {
+			return fmt.Errorf("response type %q is not supported by %q applications", responseType, settings.ApplicationType)
+		}
+	}
+
+	if !slices.Contains(appType.tokenEndpointAuthMethods, settings.TokenEndpointAuthMethod) {
+		return fmt.Errorf("token endpoint auth method %q is not supported by %q applications", settings.TokenEndpointAuthMethod, settings.ApplicationType)
+	}
+
+	if !appType.redirects && (len(settings.RedirectUris) > 0 || len(settings.PostLogoutRedirectUris) > 0) {
+		return fmt.Errorf("redirect URIs are not supported by %q applications", settings.ApplicationType)
+	}
+
+	return nil
+}
+
+// hasClientSecret returns true, if the application authenticates at the token endpoint using a client secret.
+func hasClientSecret(settings okta.ApplicationSettings) bool {
+	switch settings.TokenEndpointAuthMethod {
+	case "client_secret_basic", "client_secret_post", "client_secret_jwt":
+		return true
+	default:
+		return false
+	}
+}
+
+// applicationMatches returns true, if the Okta application already has the desired settings.
+func applicationMatches(app *okta.Application, settings okta.ApplicationSettings) bool {
+	return len(applicationDrift(app, settings)) == 0
+}
+
+// sameElements returns true, if both slices contain the same elements, regardless of their order.
+func sameElements(a []string, b []string) bool {
+	a = slices.Clone(a)
+	b = slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"github.com/jaconi-io/okta-operator/api/v1alpha1"
+	"slices"
+	"testing"
+)
+
+func TestApplicationSettingsDefaults(t *testing.T) {
+	settings := applicationSettings(&testAppClient)
+
+	if settings.ApplicationType != "web" {
+		t.Errorf("got application type %q, wanted %q", settings.ApplicationType, "web")
+	}
+	if !slices.Equal(settings.GrantTypes, []string{"refresh_token", "authorization_code"}) {
+		t.Errorf("got grant types %v, wanted %v", settings.GrantTypes, []string{"refresh_token", "authorization_code"})
+	}
+	if settings.TokenEndpointAuthMethod != "client_secret_post" {
+		t.Errorf("got token endpoint auth method %q, wanted %q", settings.TokenEndpointAuthMethod, "client_secret_post")
+	}
+	if err := validateApplicationSettings(settings); err != nil {
+		t.Errorf("got error %v, wanted none", err)
+	}
+}
+
+func TestApplicationSettingsService(t *testing.T) {
+	oktaClient := testAppClient.DeepCopy()
+	oktaClient.Spec.ApplicationType = v1alpha1.ApplicationTypeService
+
+	settings := applicationSettings(oktaClient)
+
+	if !slices.Equal(settings.GrantTypes, []string{"client_credentials"}) {
+		t.Errorf("got grant types %v, wanted %v", settings.GrantTypes, []string{"client_credentials"})
+	}
+	if err := validateApplicationSettings(settings); err != nil {
+		t.Errorf("got error %v, wanted none", err)
+	}
+}
+
+func TestValidateApplicationSettingsUnsupported(t *testing.T) {
+	oktaClient := testAppClient.DeepCopy()
+	oktaClient.Spec.ApplicationType = v1alpha1.ApplicationTypeBrowser
+	oktaClient.Spec.TokenEndpointAuthMethod = "client_secret_post"
+
+	if err := validateApplicationSettings(applicationSettings(oktaClient)); err == nil {
+		t.Errorf("got no error, wanted error for token endpoint auth method")
+	}
+
+	oktaClient = testAppClient.DeepCopy()
+	oktaClient.Spec.ApplicationType = v1alpha1.ApplicationTypeService
+	oktaClient.Spec.GrantTypes = []v1alpha1.GrantType{"authorization_code"}
+
+	if err := validateApplicationSettings(applicationSettings(oktaClient)); err == nil {
+		t.Errorf("got no error, wanted error for grant type")
+	}
+
+	oktaClient = testAppClient.DeepCopy()
+	oktaClient.Spec.ApplicationType = v1alpha1.ApplicationTypeService
+	oktaClient.Spec.RedirectUris = []string{"https://example.com/callback"}
+
+	if err := validateApplicationSettings(applicationSettings(oktaClient)); err == nil {
+		t.Errorf("got no error, wanted error for redirect URIs")
+	}
+}
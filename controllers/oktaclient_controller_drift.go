@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	oktav1alpha1 "github.com/jaconi-io/okta-operator/api/v1alpha1"
+	"github.com/jaconi-io/okta-operator/okta"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"slices"
+	"strings"
+	"time"
+)
+
+// reportDrift records drift of the OktaClient, that is not corrected due to its drift policy. The OktaClient is not
+// ready, until the drift has been resolved in Okta or its spec changes.
+func (r *OktaClientReconciler) reportDrift(oktaClient *oktav1alpha1.OktaClient, ctx context.Context, drift []string) error {
+	if setDriftCondition(oktaClient, drift) {
+		r.Recorder.Eventf(oktaClient, core.EventTypeWarning, EventReasonDriftDetected, "Okta application drifted from the spec: %s", strings.Join(drift, "; "))
+	}
+	setStatusConditions(oktaClient, ReasonDriftDetected, fmt.Errorf("Okta application drifted from the spec; see condition %s", ConditionTypeDrifted))
+
+	err := r.Status().Update(ctx, oktaClient)
+	if err != nil {
+		return fmt.Errorf("failed to update status of oktaClient %q: %w", oktaClient.Name, err)
+	}
+	return nil
+}
+
+// detectDrift compares the Okta application, trusted origins and group assignment of an OktaClient with its spec and
+// returns a human-readable description of each difference. Differences are only drift, if the spec has already been
+// synced with Okta. Otherwise, they are pending changes of the spec. The lookups are meant to be reused by the sync, see
+// lookups.
+func detectDrift(oktaClient *oktav1alpha1.OktaClient, ctx context.Context, oktaAPI okta.API) ([]string, error) {
+	var drift []string
+	for _, origin := range oktaClient.Spec.TrustedOrigins {
+		isTrustedOrigin, err := oktaAPI.IsTrustedOrigin(ctx, origin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine if %q is a trusted origin: %w", origin, err)
+		}
+		if !isTrustedOrigin {
+			drift = append(drift, fmt.Sprintf("trusted origin %q is missing", origin))
+		}
+	}
+
+	app, err := getApplication(oktaClient, ctx, oktaAPI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get application %q: %w", oktaClient.Spec.Name, err)
+	}
+	if app == nil {
+		return append(drift, fmt.Sprintf("application %q is missing", oktaClient.Status.ApplicationID)), nil
+	}
+
+	settings := applicationSettings(oktaClient)
+	settings.Keys = expectedKeys(oktaClient, time.Now())
+	drift = append(drift, applicationDrift(app, settings)...)
+
+	if oktaClient.Spec.GroupId != "" {
+		assigned, err := oktaAPI.IsApplicationGroupAssigned(ctx, app, oktaClient.Spec.GroupId)
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine if application %q is assigned to group %q: %w", oktaClient.Spec.Name, oktaClient.Spec.GroupId, err)
+		}
+		if !assigned {
+			drift = append(drift, fmt.Sprintf("application is not assigned to group %q", oktaClient.Spec.GroupId))
+		}
+	}
+
+	return drift, nil
+}
+
+// applicationDrift returns a human-readable description of each difference between the settings of the Okta
+// application and the desired settings. Keys are compared by their IDs.
+func applicationDrift(app *okta.Application, settings okta.ApplicationSettings) []string {
+	var drift []string
+	drift = append(drift, valueDrift("label", app.Label, settings.Label)...)
+	drift = append(drift, valueDrift("client URI", app.ClientUri, settings.ClientUri)...)
+	drift = append(drift, listDrift("redirect URI", app.RedirectUris, settings.RedirectUris, true)...)
+	drift = append(drift, listDrift("post logout redirect URI", app.PostLogoutRedirectUris, settings.PostLogoutRedirectUris, true)...)
+	drift = append(drift, valueDrift("application type", app.ApplicationType, settings.ApplicationType)...)
+	drift = append(drift, listDrift("grant type", app.GrantTypes, settings.GrantTypes, false)...)
+	drift = append(drift, listDrift("response type", app.ResponseTypes, settings.ResponseTypes, false)...)
+	drift = append(drift, valueDrift("token endpoint auth method", app.TokenEndpointAuthMethod, settings.TokenEndpointAuthMethod)...)
+	drift = append(drift, listDrift("key", keyIDs(app.Keys), keyIDs(settings.Keys), false)...)
+	return drift
+}
+
+// valueDrift describes the difference between the actual and the desired value of a setting, if any.
+func valueDrift(name string, actual string, desired string) []string {
+	if actual == desired {
+		return nil
+	}
+	return []string{fmt.Sprintf("%s is %q instead of %q", name, actual, desired)}
+}
+
+// listDrift describes the missing and unexpected elements of a list setting. If the order of the list is significant,
+// a different order is a difference as well.
+func listDrift(name string, actual []string, desired []string, ordered bool) []string {
+	var drift []string
+	for _, element := range desired {
+		if !slices.Contains(actual, element) {
+			drift = append(drift, fmt.Sprintf("%s %q is missing", name, element))
+		}
+	}
+	for _, element := range actual {
+		if !slices.Contains(desired, element) {
+			drift = append(drift, fmt.Sprintf("%s %q is unexpected", name, element))
+		}
+	}
+
+	if len(drift) == 0 && ordered && !slices.Equal(actual, desired) {
+		drift = append(drift, fmt.Sprintf("%ss are out of order", name))
+	} else if len(drift) == 0 && !ordered && !sameElements(actual, desired) {
+		drift = append(drift, fmt.Sprintf("%ss contain duplicates", name))
+	}
+	return drift
+}
+
+// expectedKeys returns the public keys registered with the Okta application of an OktaClient using private key JWT
+// client authentication, as recorded in its status. Only their IDs are known.
+func expectedKeys(oktaClient *oktav1alpha1.OktaClient, now time.Time) []okta.JSONWebKey {
+	status := oktaClient.Status.PrivateKey
+	if oktaClient.Spec.TokenEndpointAuthMethod != "private_key_jwt" || status == nil || status.KeyID == "" {
+		return nil
+	}
+
+	keys := []okta.JSONWebKey{{KeyID: status.KeyID}}
+	if status.PreviousKeyID != "" && status.PreviousKeyExpiresAt != nil && now.Before(status.PreviousKeyExpiresAt.Time) {
+		keys = append(keys, okta.JSONWebKey{KeyID: status.PreviousKeyID})
+	}
+	return keys
+}
+
+// isSynced returns true, if the current spec of the OktaClient has been synced with Okta.
+func isSynced(oktaClient *oktav1alpha1.OktaClient) bool {
+	return oktaClient.Status.ApplicationID != "" && oktaClient.Status.SyncedGeneration == oktaClient.Generation
+}
+
+// driftPolicy returns the drift policy of the OktaClient. Defaults to Correct.
+func driftPolicy(oktaClient *oktav1alpha1.OktaClient) oktav1alpha1.DriftPolicy {
+	if oktaClient.Spec.DriftPolicy != "" {
+		return oktaClient.Spec.DriftPolicy
+	}
+	return oktav1alpha1.DriftPolicyCorrect
+}
+
+// setDriftCondition sets the Drifted condition of the OktaClient. Drift that has been corrected is mentioned in its
+// message until the next drift detection. It returns true, if the condition changed.
+func setDriftCondition(oktaClient *oktav1alpha1.OktaClient, drift []string) bool {
+	condition := metav1.Condition{
+		Type:               ConditionTypeDrifted,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: oktaClient.Generation,
+		Reason:             ReasonNoDrift,
+		Message:            "Okta application and trusted origins match the spec",
+	}
+
+	if len(drift) > 0 && driftPolicy(oktaClient) == oktav1alpha1.DriftPolicyReport {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = ReasonDriftDetected
+		condition.Message = "Okta application drifted from the spec: " + strings.Join(drift, "; ")
+	} else if len(drift) > 0 {
+		condition.Reason = ReasonDriftCorrected
+		condition.Message = "Corrected drift of the Okta application: " + strings.Join(drift, "; ")
+	}
+
+	previous := meta.FindStatusCondition(oktaClient.Status.Conditions, ConditionTypeDrifted)
+	changed := previous == nil || previous.Status != condition.Status || previous.Reason != condition.Reason || previous.Message != condition.Message
+	meta.SetStatusCondition(&oktaClient.Status.Conditions, condition)
+	return changed
+}
@@ -0,0 +1,224 @@
+package controllers
+
+import (
+	"context"
+	"github.com/jaconi-io/okta-operator/api/v1alpha1"
+	"github.com/jaconi-io/okta-operator/okta"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newDriftTestClient returns an OktaClient with a redirect URI, a trusted origin and a group assignment.
+func newDriftTestClient(policy v1alpha1.DriftPolicy) *v1alpha1.OktaClient {
+	oktaClient := testAppClient.DeepCopy()
+	oktaClient.Spec.RedirectUris = []string{"https://example.com/callback"}
+	oktaClient.Spec.TrustedOrigins = []string{"https://example.com"}
+	oktaClient.Spec.GroupId = "group"
+	oktaClient.Spec.DriftPolicy = policy
+	return oktaClient
+}
+
+func TestApplicationDrift(t *testing.T) {
+	t.Parallel()
+	app := &okta.Application{ApplicationSettings: okta.ApplicationSettings{
+		Label:        "test-client",
+		RedirectUris: []string{"https://b.example.com", "https://a.example.com"},
+		GrantTypes:   []string{"refresh_token", "authorization_code"},
+	}}
+	settings := okta.ApplicationSettings{
+		Label:        "test-client",
+		ClientUri:    "https://example.com",
+		RedirectUris: []string{"https://a.example.com", "https://b.example.com"},
+		GrantTypes:   []string{"authorization_code", "implicit"},
+	}
+
+	drift := applicationDrift(app, settings)
+	want := []string{
+		`client URI is "" instead of "https://example.com"`,
+		`redirect URIs are out of order`,
+		`grant type "implicit" is missing`,
+		`grant type "refresh_token" is unexpected`,
+	}
+	if !slices.Equal(drift, want) {
+		t.Errorf("got drift %q, wanted %q", drift, want)
+	}
+	if applicationMatches(app, settings) {
+		t.Errorf("got matching application, wanted mismatch")
+	}
+}
+
+func TestDetectDrift(t *testing.T) {
+	t.Parallel()
+	oktaClient := newDriftTestClient(v1alpha1.DriftPolicyCorrect)
+	oktaAPI := newOktaMock()
+	app := oktaAPI.addApplication(applicationSettings(oktaClient))
+	oktaClient.Status.ApplicationID = app.ID
+
+	drift, err := detectDrift(oktaClient, context.Background(), oktaAPI)
+	want := []string{`trusted origin "https://example.com" is missing`, `application is not assigned to group "group"`}
+	if err != nil || !slices.Equal(drift, want) {
+		t.Errorf("got drift %q and error %v, wanted %q", drift, err, want)
+	}
+
+	oktaAPI.addTrustedOrigin("https://example.com")
+	_ = oktaAPI.CreateApplicationGroupAssignment(context.Background(), app, "group")
+	drift, err = detectDrift(oktaClient, context.Background(), oktaAPI)
+	if err != nil || len(drift) != 0 {
+		t.Errorf("got drift %q and error %v, wanted none", drift, err)
+	}
+
+	_ = oktaAPI.DeleteApplication(context.Background(), app)
+	drift, err = detectDrift(oktaClient, context.Background(), oktaAPI)
+	want = []string{`application "test-client" is missing`}
+	if err != nil || !slices.Equal(drift, want) {
+		t.Errorf("got drift %q and error %v, wanted %q", drift, err, want)
+	}
+}
+
+func TestExpectedKeys(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	oktaClient := testAppClient.DeepCopy()
+	oktaClient.Spec.TokenEndpointAuthMethod = "private_key_jwt"
+	oktaClient.Status.PrivateKey = &v1alpha1.PrivateKeyStatus{KeyID: "current", PreviousKeyID: "previous"}
+
+	if keys := keyIDs(expectedKeys(oktaClient, now)); !slices.Equal(keys, []string{"current"}) {
+		t.Errorf("got keys %q, wanted the current key", keys)
+	}
+
+	oktaClient.Status.PrivateKey.PreviousKeyExpiresAt = &metav1.Time{Time: now.Add(time.Hour)}
+	if keys := keyIDs(expectedKeys(oktaClient, now)); !slices.Equal(keys, []string{"current", "previous"}) {
+		t.Errorf("got keys %q, wanted the current and the previous key", keys)
+	}
+}
+
+func TestReconcileDrift(t *testing.T) {
+	t.Parallel()
+	for _, policy := range []v1alpha1.DriftPolicy{v1alpha1.DriftPolicyCorrect, v1alpha1.DriftPolicyReport} {
+		policy := policy
+		t.Run(string(policy), func(t *testing.T) {
+			t.Parallel()
+			oktaClient := newDriftTestClient(policy)
+			oktaAPI := newOktaMock()
+			recorder := record.NewFakeRecorder(10)
+			r := &OktaClientReconciler{
+				Client:         newTestClient(oktaClient, newTestSecret(nil)),
+				Recorder:       recorder,
+				Okta:           oktaAPI,
+				ResyncInterval: time.Minute,
+			}
+
+			result, err := r.Reconcile(context.Background(), testRequest)
+			if err != nil || result.RequeueAfter != time.Minute {
+				t.Fatalf("got result %+v and error %v, wanted requeue after the resync interval", result, err)
+			}
+
+			// An admin removes the redirect URI in the Okta admin console.
+			app, _ := oktaAPI.GetApplicationByLabel(context.Background(), "test-client")
+			app.RedirectUris = nil
+
+			result, err = r.Reconcile(context.Background(), testRequest)
+			if err != nil || result.RequeueAfter != time.Minute {
+				t.Fatalf("got result %+v and error %v, wanted requeue after the resync interval", result, err)
+			}
+
+			err = r.Get(context.Background(), testRequest.NamespacedName, oktaClient)
+			if err != nil {
+				t.Fatalf("error getting oktaClient: %v", err)
+			}
+			drifted := meta.FindStatusCondition(oktaClient.Status.Conditions, ConditionTypeDrifted)
+			if drifted == nil || !strings.Contains(drifted.Message, `redirect URI "https://example.com/callback" is missing`) {
+				t.Errorf("got condition %+v, wanted the missing redirect URI", drifted)
+			}
+			event := <-recorder.Events
+			if !strings.Contains(event, "https://example.com/callback") {
+				t.Errorf("got event %q, wanted the missing redirect URI", event)
+			}
+
+			if policy == v1alpha1.DriftPolicyReport {
+				if drifted.Status != "True" || drifted.Reason != ReasonDriftDetected || len(app.RedirectUris) != 0 {
+					t.Errorf("got condition %+v and redirect URIs %q, wanted reported drift", drifted, app.RedirectUris)
+				}
+				if meta.IsStatusConditionTrue(oktaClient.Status.Conditions, ConditionTypeSynced) {
+					t.Errorf("got condition %q true, wanted false", ConditionTypeSynced)
+				}
+			} else {
+				if drifted.Status != "False" || drifted.Reason != ReasonDriftCorrected || len(app.RedirectUris) != 1 {
+					t.Errorf("got condition %+v and redirect URIs %q, wanted corrected drift", drifted, app.RedirectUris)
+				}
+				if !meta.IsStatusConditionTrue(oktaClient.Status.Conditions, ConditionTypeSynced) {
+					t.Errorf("got condition %q false, wanted true", ConditionTypeSynced)
+				}
+			}
+		})
+	}
+}
+
+func TestReconcileReportedDrift(t *testing.T) {
+	t.Parallel()
+	oktaClient := newDriftTestClient(v1alpha1.DriftPolicyReport)
+	oktaAPI := newOktaMock()
+	kubernetesClient := newTestClient(oktaClient, newTestSecret(nil))
+	r := &OktaClientReconciler{
+		Client:         kubernetesClient,
+		Recorder:       record.NewFakeRecorder(10),
+		Okta:           oktaAPI,
+		ResyncInterval: time.Minute,
+	}
+
+	_, err := r.Reconcile(context.Background(), testRequest)
+	if err != nil {
+		t.Fatalf("error reconciling oktaClient: %v", err)
+	}
+
+	// An admin removes the redirect URI in the Okta admin console, while a secret rotation is requested and the secret
+	// is deleted.
+	app, _ := oktaAPI.GetApplicationByLabel(context.Background(), "test-client")
+	app.RedirectUris = nil
+	err = r.Get(context.Background(), testRequest.NamespacedName, oktaClient)
+	if err != nil {
+		t.Fatalf("error getting oktaClient: %v", err)
+	}
+	oktaClient.Annotations = map[string]string{annotationRotateSecret: "2024-01-01T00:00:00Z"}
+	err = r.Update(context.Background(), oktaClient)
+	if err != nil {
+		t.Fatalf("error updating oktaClient: %v", err)
+	}
+	err = r.Delete(context.Background(), newTestSecret(nil))
+	if err != nil {
+		t.Fatalf("error deleting secret: %v", err)
+	}
+
+	oktaAPI.lookups = 0
+	_, err = r.Reconcile(context.Background(), testRequest)
+	if err != nil {
+		t.Fatalf("error reconciling oktaClient: %v", err)
+	}
+
+	// The trusted origin, the application and the group assignment are looked up once.
+	if oktaAPI.lookups != 3 {
+		t.Errorf("got %d lookups, wanted %d", oktaAPI.lookups, 3)
+	}
+
+	err = r.Get(context.Background(), testRequest.NamespacedName, oktaClient)
+	if err != nil {
+		t.Fatalf("error getting oktaClient: %v", err)
+	}
+	if !meta.IsStatusConditionTrue(oktaClient.Status.Conditions, ConditionTypeDrifted) || len(app.RedirectUris) != 0 {
+		t.Errorf("got conditions %+v and redirect URIs %q, wanted reported drift", oktaClient.Status.Conditions, app.RedirectUris)
+	}
+	if oktaClient.Status.LastSecretRotationRequest != "2024-01-01T00:00:00Z" || oktaAPI.clientSecretsCreated != 1 {
+		t.Errorf("got last rotation request %q and %d client secrets created, wanted the requested rotation", oktaClient.Status.LastSecretRotationRequest, oktaAPI.clientSecretsCreated)
+	}
+
+	secret := newTestSecret(nil)
+	err = r.Get(context.Background(), testRequest.NamespacedName, secret)
+	if err != nil || string(secret.Data[secretKeyClientSecret]) != "secret-value-1" {
+		t.Errorf("got secret %+v and error %v, wanted the recreated secret with the rotated client secret", secret.Data, err)
+	}
+}
@@ -0,0 +1,217 @@
+package controllers
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	oktav1alpha1 "github.com/jaconi-io/okta-operator/api/v1alpha1"
+	"github.com/jaconi-io/okta-operator/okta"
+	"k8s.io/apimachinery/pkg/types"
+	"net/http"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"slices"
+	"strings"
+)
+
+const (
+	// EventHookPath is the path the Okta event hook endpoint is served at.
+	EventHookPath = "/okta/events"
+
+	// applicationIDIndex indexes OktaClients by the ID of their Okta application.
+	applicationIDIndex = "applicationID"
+
+	eventHookBufferSize  = 1024
+	eventHookMaxBodySize = 1 << 20
+)
+
+// Okta events with these type prefixes trigger reconciliations. See
+// https://developer.okta.com/docs/reference/api/event-types/.
+var (
+	applicationEventTypePrefixes   = []string{"application.lifecycle.", "application.user_membership.", "group.application_assignment."}
+	trustedOriginEventTypePrefixes = []string{"security.trusted_origin."}
+)
+
+// EventHook receives Okta event hooks and triggers the reconciliation of the OktaClients affected by changes made in
+// Okta, so they are corrected within seconds instead of on the next resync. See
+// https://developer.okta.com/docs/concepts/event-hooks/.
+type EventHook struct {
+	reader client.Reader
+	secret string
+	events chan event.GenericEvent
+
+	// defaultOrg is the Okta organization of OktaClients without an organization reference. It may be nil.
+	defaultOrg okta.API
+}
+
+// NewEventHook returns an event hook looking up the affected OktaClients with the given reader. Events sent by the
+// given default organization affect OktaClients without an organization reference. Requests must have the secret as
+// Authorization header. An empty secret disables authentication, so it must only be used in tests.
+func NewEventHook(reader client.Reader, defaultOrg okta.API, secret string) *EventHook {
+	return &EventHook{
+		reader:     reader,
+		secret:     secret,
+		events:     make(chan event.GenericEvent, eventHookBufferSize),
+		defaultOrg: defaultOrg,
+	}
+}
+
+// Events returns the channel the OktaClients affected by events are sent to. See OktaClientReconciler.Events.
+func (h *EventHook) Events() <-chan event.GenericEvent {
+	return h.events
+}
+
+// eventHookRequest is the body of an event hook request. Only the fields used by the operator are decoded.
+type eventHookRequest struct {
+	// Source is the URL of the event hook in the Okta organization sending the request.
+	Source string `json:"source"`
+	Data   struct {
+		Events []oktaEvent `json:"events"`
+	} `json:"data"`
+}
+
+type oktaEvent struct {
+	EventType string        `json:"eventType"`
+	Target    []eventTarget `json:"target"`
+}
+
+type eventTarget struct {
+	ID          string `json:"id"`
+	Type        string `json:"type"`
+	AlternateID string `json:"alternateId"`
+	DisplayName string `json:"displayName"`
+}
+
+// ServeHTTP answers the one-time verification request of Okta and handles the events delivered by the event hook.
+func (h *EventHook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log := ctrllog.FromContext(r.Context()).WithName("eventHook")
+	if h.secret != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte(h.secret)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		challenge := r.Header.Get("X-Okta-Verification-Challenge")
+		if challenge == "" {
+			http.Error(w, "missing verification challenge", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{"verification": challenge})
+
+	case http.MethodPost:
+		request := &eventHookRequest{}
+		err := json.NewDecoder(http.MaxBytesReader(w, r.Body, eventHookMaxBodySize)).Decode(request)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid event hook request: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		oktaClients, err := h.affectedOktaClients(r.Context(), request.Source, request.Data.Events)
+		if err != nil {
+			log.Error(err, "Failed to look up OktaClients affected by Okta events")
+			http.Error(w, "failed to look up affected OktaClients", http.StatusInternalServerError)
+			return
+		}
+
+		for _, oktaClient := range oktaClients {
+			select {
+			case h.events <- event.GenericEvent{Object: oktaClient}:
+				log.Info("Reconciling OktaClient affected by Okta event", "oktaClient", client.ObjectKeyFromObject(oktaClient))
+			default:
+				// Okta retries undelivered events, but not dropped ones. The change is picked up by the next resync.
+				// Replicas that are not the leader never consume events and drop them once the buffer is full.
+				log.Info("Dropped Okta event", "oktaClient", client.ObjectKeyFromObject(oktaClient))
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// affectedOktaClients returns the OktaClients managing the applications or trusted origins targeted by the events.
+// Application IDs are unique across Okta organizations, but trusted origins are only looked up in the organizations
+// sending the events. Events of other types are ignored.
+func (h *EventHook) affectedOktaClients(ctx context.Context, source string, events []oktaEvent) ([]*oktav1alpha1.OktaClient, error) {
+	orgHosts := h.orgHosts(source)
+	var result []*oktav1alpha1.OktaClient
+	seen := map[types.NamespacedName]bool{}
+	add := func(index string, value string) error {
+		oktaClients := &oktav1alpha1.OktaClientList{}
+		err := h.reader.List(ctx, oktaClients, client.MatchingFields{index: value})
+		if err != nil {
+			return fmt.Errorf("failed to list oktaClients by %s %q: %w", index, value, err)
+		}
+		for i := range oktaClients.Items {
+			oktaClient := &oktaClients.Items[i]
+			if !seen[client.ObjectKeyFromObject(oktaClient)] {
+				seen[client.ObjectKeyFromObject(oktaClient)] = true
+				result = append(result, oktaClient)
+			}
+		}
+		return nil
+	}
+
+	for _, e := range events {
+		for _, target := range e.Target {
+			var err error
+			switch {
+			case hasAnyPrefix(e.EventType, applicationEventTypePrefixes) && target.Type == "AppInstance" && target.ID != "":
+				err = add(applicationIDIndex, target.ID)
+			case hasAnyPrefix(e.EventType, trustedOriginEventTypePrefixes):
+				// Trusted origins are not tracked by their ID. Okta reports their origin as alternate ID or display name.
+				for _, orgHost := range orgHosts {
+					for _, origin := range []string{target.AlternateID, target.DisplayName} {
+						if origin != "" && err == nil {
+							err = add(trustedOriginsIndex, trustedOriginKey(orgHost, origin))
+						}
+					}
+				}
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// orgHosts returns the hosts the trusted origins of the event hook source are indexed by: the host of the Okta
+// organization sending the events and the empty host of OktaClients, whose organization is not known yet. Requests
+// without a source are attributed to the default organization.
+func (h *EventHook) orgHosts(source string) []string {
+	hosts := []string{""}
+	if source == "" && h.defaultOrg != nil {
+		source = h.defaultOrg.OrgURL()
+	}
+	if host := urlHost(source); host != "" {
+		hosts = append(hosts, host)
+	}
+	return hosts
+}
+
+// hasAnyPrefix returns true, if the string starts with any of the prefixes.
+func hasAnyPrefix(s string, prefixes []string) bool {
+	return slices.ContainsFunc(prefixes, func(prefix string) bool {
+		return strings.HasPrefix(s, prefix)
+	})
+}
+
+// indexApplicationID returns the ID of the Okta application of an OktaClient for the applicationIDIndex.
+func indexApplicationID(obj client.Object) []string {
+	oktaClient := obj.(*oktav1alpha1.OktaClient)
+
+	var ids []string
+	for _, id := range []string{oktaClient.Spec.ExistingApplicationID, oktaClient.Status.ApplicationID} {
+		if id != "" && !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"encoding/json"
+	"github.com/jaconi-io/okta-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// newEventHookTestClients returns OktaClients managing the applications app-a and app-b with the trusted origin
+// https://a.example.com and https://b.example.com respectively.
+func newEventHookTestClients() (*v1alpha1.OktaClient, *v1alpha1.OktaClient) {
+	a := &v1alpha1.OktaClient{
+		ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "default"},
+		Spec:       v1alpha1.OktaClientSpec{Name: "a", TrustedOrigins: []string{"https://a.example.com"}},
+		Status:     v1alpha1.OktaClientStatus{ApplicationID: "app-a", OrgURL: "https://example.okta.com"},
+	}
+	b := &v1alpha1.OktaClient{
+		ObjectMeta: metav1.ObjectMeta{Name: "b", Namespace: "default"},
+		Spec:       v1alpha1.OktaClientSpec{Name: "b", ExistingApplicationID: "app-b", TrustedOrigins: []string{"https://b.example.com"}},
+	}
+	return a, b
+}
+
+// postEvents delivers the given events to the event hook and returns the names of the OktaClients enqueued.
+func postEvents(t *testing.T, eventHook *EventHook, events string) []string {
+	t.Helper()
+	return postEventsFrom(t, eventHook, "", events)
+}
+
+// postEventsFrom delivers the given events to the event hook as sent by the event hook with the given source URL and
+// returns the names of the OktaClients enqueued.
+func postEventsFrom(t *testing.T, eventHook *EventHook, source string, events string) []string {
+	t.Helper()
+	body := `{"source":` + strconv.Quote(source) + `,"data":{"events":` + events + `}}`
+	req := httptest.NewRequest(http.MethodPost, EventHookPath, strings.NewReader(body))
+	req.Header.Set("Authorization", "secret")
+
+	rec := httptest.NewRecorder()
+	eventHook.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("got status %d, wanted %d", rec.Code, http.StatusNoContent)
+	}
+
+	var names []string
+	for len(eventHook.events) > 0 {
+		names = append(names, (<-eventHook.events).Object.GetName())
+	}
+	return names
+}
+
+func TestEventHookVerification(t *testing.T) {
+	t.Parallel()
+	eventHook := NewEventHook(newTestClient(), newOktaMock(), "secret")
+	req := httptest.NewRequest(http.MethodGet, EventHookPath, nil)
+	req.Header.Set("Authorization", "secret")
+	req.Header.Set("X-Okta-Verification-Challenge", "challenge")
+
+	rec := httptest.NewRecorder()
+	eventHook.ServeHTTP(rec, req)
+
+	var body map[string]string
+	err := json.NewDecoder(rec.Body).Decode(&body)
+	if rec.Code != http.StatusOK || err != nil || body["verification"] != "challenge" {
+		t.Errorf("got status %d, body %v and error %v, wanted the verification challenge", rec.Code, body, err)
+	}
+}
+
+func TestEventHookUnauthorized(t *testing.T) {
+	t.Parallel()
+	eventHook := NewEventHook(newTestClient(), newOktaMock(), "secret")
+	req := httptest.NewRequest(http.MethodPost, EventHookPath, strings.NewReader(`{}`))
+	req.Header.Set("Authorization", "other")
+
+	rec := httptest.NewRecorder()
+	eventHook.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("got status %d, wanted %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestEventHookApplicationEvents(t *testing.T) {
+	t.Parallel()
+	eventHook := NewEventHook(newTestClient(newEventHookTestClients()), newOktaMock(), "secret")
+
+	names := postEvents(t, eventHook, `[
+		{"eventType": "application.lifecycle.update", "target": [{"id": "app-a", "type": "AppInstance"}]},
+		{"eventType": "application.user_membership.remove", "target": [{"id": "user", "type": "User"}, {"id": "app-a", "type": "AppInstance"}]},
+		{"eventType": "group.application_assignment.remove", "target": [{"id": "app-b", "type": "AppInstance"}]},
+		{"eventType": "application.lifecycle.delete", "target": [{"id": "unknown", "type": "AppInstance"}]},
+		{"eventType": "user.session.start", "target": [{"id": "app-a", "type": "AppInstance"}]}
+	]`)
+	if len(names) != 2 || names[0] != "a" || names[1] != "b" {
+		t.Errorf("got oktaClients %q, wanted %q", names, []string{"a", "b"})
+	}
+}
+
+func TestEventHookTrustedOriginEvents(t *testing.T) {
+	t.Parallel()
+	eventHook := NewEventHook(newTestClient(newEventHookTestClients()), newOktaMock(), "secret")
+
+	names := postEvents(t, eventHook, `[
+		{"eventType": "security.trusted_origin.delete", "target": [{"id": "tos1", "type": "TrustedOrigin", "displayName": "https://b.example.com"}]}
+	]`)
+	if len(names) != 1 || names[0] != "b" {
+		t.Errorf("got oktaClients %q, wanted %q", names, []string{"b"})
+	}
+}
+
+func TestEventHookTrustedOriginEventsOfOrg(t *testing.T) {
+	t.Parallel()
+	a, b := newEventHookTestClients()
+	other := b.DeepCopy()
+	other.Name = "other"
+	b.Status.OrgURL = "https://example.okta.com"
+	other.Spec.OrgRef = &v1alpha1.OrgReference{Kind: v1alpha1.OktaOrgKind, Name: "preview"}
+	other.Status.OrgURL = "https://example.oktapreview.com/"
+	eventHook := NewEventHook(newTestClient(a, b, other), newOktaMock(), "secret")
+
+	events := `[
+		{"eventType": "security.trusted_origin.delete", "target": [{"id": "tos1", "type": "TrustedOrigin", "displayName": "https://b.example.com"}]}
+	]`
+	names := postEventsFrom(t, eventHook, "https://example.oktapreview.com/api/v1/eventHooks/who1", events)
+	if len(names) != 1 || names[0] != "other" {
+		t.Errorf("got oktaClients %q, wanted %q", names, []string{"other"})
+	}
+
+	names = postEventsFrom(t, eventHook, "https://example.okta.com/api/v1/eventHooks/who2", events)
+	if len(names) != 1 || names[0] != "b" {
+		t.Errorf("got oktaClients %q, wanted %q", names, []string{"b"})
+	}
+}
@@ -0,0 +1,254 @@
+package controllers
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	oktav1alpha1 "github.com/jaconi-io/okta-operator/api/v1alpha1"
+	"github.com/jaconi-io/okta-operator/okta"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"math/big"
+	"time"
+)
+
+const (
+	secretKeyPrivateKey = "OKTA_CLIENT_PRIVATE_KEY"
+	secretKeyKeyID      = "OKTA_CLIENT_KEY_ID"
+
+	defaultKeyAlgorithm   = "RS256"
+	defaultKeyGracePeriod = 24 * time.Hour
+)
+
+// updateKeys determines the public keys to register with the Okta application of an OktaClient using private key JWT
+// client authentication. A new key pair is generated, if the secret does not contain one yet or if the current key pair
+// is due for rotation. The previous public key stays registered during the grace period. If a new key pair has been
+// generated, its PEM encoded private key is returned, so it can be stored in the secret. The status of the key pairs is
+// returned as well, but not recorded in the OktaClient. It only applies, once the private key is stored.
+func updateKeys(oktaClient *oktav1alpha1.OktaClient, secret *core.Secret, app *okta.Application, now time.Time) ([]okta.JSONWebKey, []byte, *oktav1alpha1.PrivateKeyStatus, error) {
+	algorithm, interval, gracePeriod := keySettings(oktaClient)
+
+	status := oktaClient.Status.PrivateKey.DeepCopy()
+	if status == nil {
+		status = &oktav1alpha1.PrivateKeyStatus{}
+	}
+
+	var current crypto.Signer
+	if secret != nil {
+		// Keys that cannot be parsed are replaced.
+		current, _ = parsePrivateKey(secretCredential(secret, secretKeyPrivateKey))
+	}
+
+	var privateKeyPEM []byte
+	if current == nil || keyAlgorithm(current) != algorithm || rotationDue(status, interval, now) {
+		var err error
+		current, err = generateKey(algorithm)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to generate %s key pair: %w", algorithm, err)
+		}
+
+		privateKeyPEM, err = encodePrivateKey(current)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to encode %s private key: %w", algorithm, err)
+		}
+
+		if status.KeyID != "" {
+			status.PreviousKeyID = status.KeyID
+			status.PreviousKeyExpiresAt = &metav1.Time{Time: now.Add(gracePeriod)}
+		}
+		status.RotatedAt = &metav1.Time{Time: now}
+	}
+
+	currentKey, err := publicKey(current, algorithm)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	status.KeyID = currentKey.KeyID
+	if status.RotatedAt == nil {
+		status.RotatedAt = &metav1.Time{Time: now}
+	}
+
+	keys := []okta.JSONWebKey{currentKey}
+
+	// Keep the previous public key registered during the grace period.
+	if status.PreviousKeyID != "" && status.PreviousKeyID != status.KeyID && status.PreviousKeyExpiresAt != nil && now.Before(status.PreviousKeyExpiresAt.Time) {
+		if app != nil {
+			for _, key := range app.Keys {
+				if key.KeyID == status.PreviousKeyID {
+					keys = append(keys, key)
+				}
+			}
+		}
+	} else {
+		status.PreviousKeyID = ""
+		status.PreviousKeyExpiresAt = nil
+	}
+
+	return keys, privateKeyPEM, status, nil
+}
+
+// nextKeyRotation returns the duration until the key pairs of the OktaClient have to be reconciled again, either
+// because the current key pair is due for rotation or because the grace period of the previous key ends. Zero is
+// returned, if no such event is pending.
+func nextKeyRotation(oktaClient *oktav1alpha1.OktaClient, now time.Time) time.Duration {
+	status := oktaClient.Status.PrivateKey
+	if status == nil || oktaClient.Spec.TokenEndpointAuthMethod != "private_key_jwt" {
+		return 0
+	}
+
+	// Events that are already due are handled right away.
+	var next time.Duration
+	_, interval, _ := keySettings(oktaClient)
+	if interval > 0 && status.RotatedAt != nil {
+		next = max(status.RotatedAt.Add(interval).Sub(now), time.Second)
+	}
+	if status.PreviousKeyExpiresAt != nil {
+		expires := max(status.PreviousKeyExpiresAt.Sub(now), time.Second)
+		if next == 0 || expires < next {
+			next = expires
+		}
+	}
+
+	return next
+}
+
+// keySettings returns the key algorithm, rotation interval and grace period of the OktaClient, applying defaults.
+func keySettings(oktaClient *oktav1alpha1.OktaClient) (string, time.Duration, time.Duration) {
+	algorithm := defaultKeyAlgorithm
+	var interval time.Duration
+	gracePeriod := defaultKeyGracePeriod
+
+	privateKey := oktaClient.Spec.PrivateKey
+	if privateKey != nil {
+		if privateKey.Algorithm != "" {
+			algorithm = privateKey.Algorithm
+		}
+		if privateKey.RotationInterval != nil {
+			interval = privateKey.RotationInterval.Duration
+		}
+		if privateKey.GracePeriod != nil {
+			gracePeriod = privateKey.GracePeriod.Duration
+		}
+	}
+
+	return algorithm, interval, gracePeriod
+}
+
+// rotationDue returns true, if the current key pair is older than the rotation interval.
+func rotationDue(status *oktav1alpha1.PrivateKeyStatus, interval time.Duration, now time.Time) bool {
+	return interval > 0 && status.RotatedAt != nil && !now.Before(status.RotatedAt.Add(interval))
+}
+
+// generateKey generates a new key pair for the given algorithm.
+func generateKey(algorithm string) (crypto.Signer, error) {
+	switch algorithm {
+	case "RS256":
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case "ES256":
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported key algorithm %q", algorithm)
+	}
+}
+
+// keyAlgorithm returns the algorithm of a key pair generated by generateKey.
+func keyAlgorithm(key crypto.Signer) string {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return "RS256"
+	case *ecdsa.PrivateKey:
+		if k.Curve == elliptic.P256() {
+			return "ES256"
+		}
+	}
+	return ""
+}
+
+// encodePrivateKey as PEM encoded PKCS #8.
+func encodePrivateKey(key crypto.Signer) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// parsePrivateKey from PEM encoded PKCS #8.
+func parsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM encoded private key found")
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return signer, nil
+}
+
+// publicKey returns the public key of a key pair as JSON Web Key. The key ID is the RFC 7638 thumbprint of the key.
+func publicKey(key crypto.Signer, algorithm string) (okta.JSONWebKey, error) {
+	jwk := okta.JSONWebKey{
+		Use:       "sig",
+		Algorithm: algorithm,
+	}
+
+	var thumbprintInput []byte
+	var err error
+	switch pub := key.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		thumbprintInput, err = json.Marshal(struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.KeyType, jwk.N})
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		jwk.KeyType = "EC"
+		jwk.Curve = pub.Curve.Params().Name
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub.X.FillBytes(make([]byte, size)))
+		jwk.Y = base64.RawURLEncoding.EncodeToString(pub.Y.FillBytes(make([]byte, size)))
+		thumbprintInput, err = json.Marshal(struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{jwk.Curve, jwk.KeyType, jwk.X, jwk.Y})
+	default:
+		return okta.JSONWebKey{}, fmt.Errorf("unsupported public key type %T", pub)
+	}
+	if err != nil {
+		return okta.JSONWebKey{}, err
+	}
+
+	thumbprint := sha256.Sum256(thumbprintInput)
+	jwk.KeyID = base64.RawURLEncoding.EncodeToString(thumbprint[:])
+
+	return jwk, nil
+}
+
+// keyIDs returns the IDs of the given keys.
+func keyIDs(keys []okta.JSONWebKey) []string {
+	var ids []string
+	for _, key := range keys {
+		ids = append(ids, key.KeyID)
+	}
+	return ids
+}
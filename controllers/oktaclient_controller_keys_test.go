@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"context"
+	"github.com/jaconi-io/okta-operator/api/v1alpha1"
+	"github.com/jaconi-io/okta-operator/okta"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+	"time"
+)
+
+var testKeyClient = v1alpha1.OktaClient{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:      "test-client",
+		Namespace: "default",
+	},
+	Spec: v1alpha1.OktaClientSpec{
+		Name:                    "test-client",
+		TokenEndpointAuthMethod: "private_key_jwt",
+		PrivateKey: &v1alpha1.PrivateKey{
+			RotationInterval: &metav1.Duration{Duration: time.Hour},
+			GracePeriod:      &metav1.Duration{Duration: time.Minute},
+		},
+	},
+}
+
+func TestUpdateKeysNoSecret(t *testing.T) {
+	oktaClient := testKeyClient.DeepCopy()
+	now := time.Now()
+
+	keys, privateKey, status, err := updateKeys(oktaClient, nil, nil, now)
+	if err != nil {
+		t.Fatalf("error updating keys: %v", err)
+	}
+	if oktaClient.Status.PrivateKey != nil {
+		t.Errorf("got private key status %+v, wanted none before the key is stored", oktaClient.Status.PrivateKey)
+	}
+	oktaClient.Status.PrivateKey = status
+	if len(keys) != 1 || privateKey == nil {
+		t.Fatalf("got %d keys and private key %v, wanted a new key pair", len(keys), privateKey != nil)
+	}
+	if keys[0].KeyType != "RSA" || keys[0].KeyID != oktaClient.Status.PrivateKey.KeyID {
+		t.Errorf("got key %+v, wanted RSA key %q", keys[0], oktaClient.Status.PrivateKey.KeyID)
+	}
+
+	// The key pair is kept, once it is stored in the secret.
+	secret := &core.Secret{Data: map[string][]byte{secretKeyPrivateKey: privateKey}}
+	keys, privateKey, _, err = updateKeys(oktaClient, secret, nil, now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("error updating keys: %v", err)
+	}
+	if len(keys) != 1 || privateKey != nil {
+		t.Errorf("got %d keys and private key %v, wanted the existing key pair", len(keys), privateKey != nil)
+	}
+}
+
+func TestUpdateKeysRotation(t *testing.T) {
+	oktaClient := testKeyClient.DeepCopy()
+	now := time.Now()
+
+	keys, privateKey, status, _ := updateKeys(oktaClient, nil, nil, now)
+	oktaClient.Status.PrivateKey = status
+	app := &okta.Application{}
+	app.Keys = keys
+	secret := &core.Secret{Data: map[string][]byte{secretKeyPrivateKey: privateKey}}
+	previousKeyID := oktaClient.Status.PrivateKey.KeyID
+
+	// The rotation interval has passed, the previous key stays registered during the grace period.
+	keys, privateKey, status, err := updateKeys(oktaClient, secret, app, now.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("error updating keys: %v", err)
+	}
+	if oktaClient.Status.PrivateKey.KeyID != previousKeyID {
+		t.Errorf("got key %q, wanted %q until the new key is stored", oktaClient.Status.PrivateKey.KeyID, previousKeyID)
+	}
+	oktaClient.Status.PrivateKey = status
+	if len(keys) != 2 || privateKey == nil {
+		t.Fatalf("got %d keys and private key %v, wanted a new and the previous key", len(keys), privateKey != nil)
+	}
+	if oktaClient.Status.PrivateKey.PreviousKeyID != previousKeyID || keys[1].KeyID != previousKeyID {
+		t.Errorf("got previous key %q, wanted %q", oktaClient.Status.PrivateKey.PreviousKeyID, previousKeyID)
+	}
+	if next := nextKeyRotation(oktaClient, now.Add(2*time.Hour)); next != time.Minute {
+		t.Errorf("got next key rotation in %s, wanted %s", next, time.Minute)
+	}
+
+	// The grace period has passed, the previous key is removed.
+	app.Keys = keys
+	secret.Data[secretKeyPrivateKey] = privateKey
+	keys, _, status, err = updateKeys(oktaClient, secret, app, now.Add(2*time.Hour+2*time.Minute))
+	if err != nil {
+		t.Fatalf("error updating keys: %v", err)
+	}
+	oktaClient.Status.PrivateKey = status
+	if len(keys) != 1 || oktaClient.Status.PrivateKey.PreviousKeyID != "" {
+		t.Errorf("got %d keys and previous key %q, wanted only the current key", len(keys), oktaClient.Status.PrivateKey.PreviousKeyID)
+	}
+}
+
+func TestUpdateKeysEllipticCurve(t *testing.T) {
+	oktaClient := testKeyClient.DeepCopy()
+	oktaClient.Spec.PrivateKey.Algorithm = "ES256"
+
+	keys, privateKey, _, err := updateKeys(oktaClient, nil, nil, time.Now())
+	if err != nil {
+		t.Fatalf("error updating keys: %v", err)
+	}
+	if keys[0].KeyType != "EC" || keys[0].Curve != "P-256" || len(keys[0].X) != 43 {
+		t.Errorf("got key %+v, wanted P-256 key", keys[0])
+	}
+
+	key, err := parsePrivateKey(privateKey)
+	if err != nil || keyAlgorithm(key) != "ES256" {
+		t.Errorf("got private key with algorithm %q and error %v, wanted %q", keyAlgorithm(key), err, "ES256")
+	}
+}
+
+func TestUpdateApplicationPrivateKeyJWT(t *testing.T) {
+	t.Parallel()
+	oktaAPI := newOktaMock()
+	oktaClient := testKeyClient.DeepCopy()
+
+	err := updateApplication(oktaClient, context.Background(), testRequest, newTestClient(), oktaAPI)
+	if err != nil {
+		t.Errorf("error updating application: %v", err)
+	}
+	app := oktaAPI.applications[oktaClient.Spec.Name]
+	if app == nil || len(app.Keys) != 1 || app.Keys[0].KeyID != oktaClient.Status.PrivateKey.KeyID {
+		t.Errorf("got application %+v, wanted application with key %q", app, oktaClient.Status.PrivateKey.KeyID)
+	}
+}
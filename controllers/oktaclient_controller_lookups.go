@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"context"
+	"github.com/jaconi-io/okta-operator/okta"
+)
+
+// lookups wraps the Okta API for a single reconciliation. Trusted origins, applications and group assignments are only
+// looked up once, so detecting drift before syncing the OktaClient does not double the requests to Okta. Changes made
+// through the wrapper update the results, all other calls are passed through.
+type lookups struct {
+	okta.API
+
+	trustedOrigins   map[string]bool
+	applications     map[string]*okta.Application
+	groupAssignments map[groupAssignment]bool
+}
+
+// groupAssignment identifies the assignment of an application to a group.
+type groupAssignment struct {
+	applicationID string
+	groupID       string
+}
+
+func newLookups(oktaAPI okta.API) *lookups {
+	return &lookups{
+		API:              oktaAPI,
+		trustedOrigins:   map[string]bool{},
+		applications:     map[string]*okta.Application{},
+		groupAssignments: map[groupAssignment]bool{},
+	}
+}
+
+func (l *lookups) IsTrustedOrigin(ctx context.Context, origin string) (bool, error) {
+	if isTrustedOrigin, ok := l.trustedOrigins[origin]; ok {
+		return isTrustedOrigin, nil
+	}
+
+	isTrustedOrigin, err := l.API.IsTrustedOrigin(ctx, origin)
+	if err != nil {
+		return false, err
+	}
+	l.trustedOrigins[origin] = isTrustedOrigin
+	return isTrustedOrigin, nil
+}
+
+func (l *lookups) CreateTrustedOrigin(ctx context.Context, origin string) error {
+	delete(l.trustedOrigins, origin)
+	err := l.API.CreateTrustedOrigin(ctx, origin)
+	if err == nil {
+		l.trustedOrigins[origin] = true
+	}
+	return err
+}
+
+func (l *lookups) DeleteTrustedOrigin(ctx context.Context, origin string) error {
+	delete(l.trustedOrigins, origin)
+	return l.API.DeleteTrustedOrigin(ctx, origin)
+}
+
+func (l *lookups) GetApplicationByID(ctx context.Context, id string) (*okta.Application, error) {
+	if app, ok := l.applications[id]; ok {
+		return app, nil
+	}
+
+	app, err := l.API.GetApplicationByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	l.applications[id] = app
+	return app, nil
+}
+
+func (l *lookups) DeleteApplication(ctx context.Context, app *okta.Application) error {
+	delete(l.applications, app.ID)
+	return l.API.DeleteApplication(ctx, app)
+}
+
+func (l *lookups) IsApplicationGroupAssigned(ctx context.Context, app *okta.Application, groupID string) (bool, error) {
+	key := groupAssignment{applicationID: app.ID, groupID: groupID}
+	if assigned, ok := l.groupAssignments[key]; ok {
+		return assigned, nil
+	}
+
+	assigned, err := l.API.IsApplicationGroupAssigned(ctx, app, groupID)
+	if err != nil {
+		return false, err
+	}
+	l.groupAssignments[key] = assigned
+	return assigned, nil
+}
+
+// CreateApplicationGroupAssignment assigns the application to the group, unless it is known to be assigned already.
+func (l *lookups) CreateApplicationGroupAssignment(ctx context.Context, app *okta.Application, groupID string) error {
+	key := groupAssignment{applicationID: app.ID, groupID: groupID}
+	if l.groupAssignments[key] {
+		return nil
+	}
+
+	err := l.API.CreateApplicationGroupAssignment(ctx, app, groupID)
+	if err != nil {
+		return err
+	}
+	l.groupAssignments[key] = true
+	return nil
+}
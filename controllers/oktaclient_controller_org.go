@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	oktav1alpha1 "github.com/jaconi-io/okta-operator/api/v1alpha1"
+	"github.com/jaconi-io/okta-operator/okta"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"net/url"
+	"strings"
+)
+
+//+kubebuilder:rbac:groups=okta.jaconi.io,resources=oktaorgs,verbs=get;list;watch
+//+kubebuilder:rbac:groups=okta.jaconi.io,resources=clusteroktaorgs,verbs=get;list;watch
+
+// oktaAPI returns the API of the Okta organization referenced by the OktaClient. OktaClients without an organization
+// reference use the organization configured for the operator.
+func (r *OktaClientReconciler) oktaAPI(oktaClient *oktav1alpha1.OktaClient, ctx context.Context) (okta.API, error) {
+	ref := oktaClient.Spec.OrgRef
+	if ref == nil {
+		if r.Okta == nil {
+			return nil, errors.New("no Okta organization is configured for the operator; reference an OktaOrg or ClusterOktaOrg")
+		}
+		return r.Okta, nil
+	}
+	if r.Orgs == nil {
+		return nil, errors.New("Okta organization references are not supported")
+	}
+
+	var spec oktav1alpha1.OktaOrgSpec
+	var secretNamespace func(namespace string) (string, error)
+	switch ref.Kind {
+	case oktav1alpha1.ClusterOktaOrgKind:
+		org := &oktav1alpha1.ClusterOktaOrg{}
+		err := r.Get(ctx, types.NamespacedName{Name: ref.Name}, org)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get clusterOktaOrg %q: %w", ref.Name, err)
+		}
+		spec = org.Spec
+		secretNamespace = func(namespace string) (string, error) {
+			if namespace == "" {
+				return "", fmt.Errorf("secret of clusterOktaOrg %q has no namespace", ref.Name)
+			}
+			return namespace, nil
+		}
+	case oktav1alpha1.OktaOrgKind, "":
+		org := &oktav1alpha1.OktaOrg{}
+		err := r.Get(ctx, types.NamespacedName{Namespace: oktaClient.Namespace, Name: ref.Name}, org)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get oktaOrg %q: %w", ref.Name, err)
+		}
+		spec = org.Spec
+		secretNamespace = func(string) (string, error) {
+			return oktaClient.Namespace, nil
+		}
+	default:
+		return nil, fmt.Errorf("unsupported Okta organization kind %q", ref.Kind)
+	}
+
+	var credentials okta.Credentials
+	switch {
+	case spec.OAuth != nil:
+		secretRef := spec.OAuth.PrivateKeySecretRef
+		namespace, err := secretNamespace(secretRef.Namespace)
+		if err != nil {
+			return nil, err
+		}
+		privateKey, err := r.secretValue(ctx, ref.Name, namespace, secretRef.Name, secretRef.Key, oktav1alpha1.DefaultPrivateKeyKey)
+		if err != nil {
+			return nil, err
+		}
+		credentials = okta.Credentials{
+			ClientID:     spec.OAuth.ClientID,
+			PrivateKey:   privateKey,
+			PrivateKeyID: spec.OAuth.PrivateKeyID,
+			Scopes:       spec.OAuth.Scopes,
+		}
+	case spec.CredentialsSecretRef != nil:
+		secretRef := spec.CredentialsSecretRef
+		namespace, err := secretNamespace(secretRef.Namespace)
+		if err != nil {
+			return nil, err
+		}
+		token, err := r.secretValue(ctx, ref.Name, namespace, secretRef.Name, secretRef.Key, oktav1alpha1.DefaultCredentialsKey)
+		if err != nil {
+			return nil, err
+		}
+		credentials = okta.Credentials{Token: string(token)}
+	default:
+		return nil, fmt.Errorf("Okta organization %q has neither a credentials secret nor OAuth credentials", ref.Name)
+	}
+
+	oktaAPI, err := r.Orgs.Client(ctx, spec.OrgURL, credentials)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client of Okta organization %q: %w", ref.Name, err)
+	}
+	return oktaAPI, nil
+}
+
+// secretValue returns the value of the key in the secret of an Okta organization. An empty key selects the default
+// key.
+func (r *OktaClientReconciler) secretValue(ctx context.Context, org string, namespace string, name string, key string, defaultKey string) ([]byte, error) {
+	if key == "" {
+		key = defaultKey
+	}
+	secret := &core.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get secret %q of Okta organization %q: %w", name, org, err)
+	}
+	value := secret.Data[key]
+	if len(value) == 0 {
+		return nil, fmt.Errorf("secret %q of Okta organization %q has no key %q", name, org, key)
+	}
+	return value, nil
+}
+
+// urlHost returns the host of the URL or an empty string, if it cannot be parsed. Okta organizations are identified by
+// the host of their URL, no matter which OktaOrg or ClusterOktaOrg references them.
+func urlHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Host)
+}
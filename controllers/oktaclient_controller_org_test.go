@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"context"
+	"github.com/jaconi-io/okta-operator/api/v1alpha1"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func TestOktaAPIDefaultOrg(t *testing.T) {
+	t.Parallel()
+	oktaClient := testAppClient.DeepCopy()
+	defaultOrg := newOktaMock()
+
+	r := &OktaClientReconciler{Client: newTestClient(), Okta: defaultOrg, Orgs: newOrgsMock()}
+	oktaAPI, err := r.oktaAPI(oktaClient, context.Background())
+	if err != nil || oktaAPI != defaultOrg {
+		t.Errorf("got API %v and error %v, wanted the default organization", oktaAPI, err)
+	}
+
+	r.Okta = nil
+	_, err = r.oktaAPI(oktaClient, context.Background())
+	if err == nil {
+		t.Errorf("expected error without default organization")
+	}
+}
+
+func TestOktaAPIOktaOrg(t *testing.T) {
+	t.Parallel()
+	oktaClient := testAppClient.DeepCopy()
+	oktaClient.Spec.OrgRef = &v1alpha1.OrgReference{Name: "preview"}
+	org := &v1alpha1.OktaOrg{
+		ObjectMeta: metav1.ObjectMeta{Name: "preview", Namespace: "default"},
+		Spec: v1alpha1.OktaOrgSpec{
+			OrgURL:               "https://preview.example.com",
+			CredentialsSecretRef: &v1alpha1.CredentialsSecretReference{Name: "okta-preview"},
+		},
+	}
+	secret := &core.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "okta-preview", Namespace: "default"},
+		Data:       map[string][]byte{"OKTA_CLIENT_TOKEN": []byte("token")},
+	}
+
+	orgs := newOrgsMock()
+	r := &OktaClientReconciler{Client: newTestClient(org, secret), Okta: newOktaMock(), Orgs: orgs}
+	oktaAPI, err := r.oktaAPI(oktaClient, context.Background())
+	if err != nil || oktaAPI != orgs.orgs["https://preview.example.com"] {
+		t.Errorf("got API %v and error %v, wanted the preview organization", oktaAPI, err)
+	}
+	if token := orgs.credentials["https://preview.example.com"].Token; token != "token" {
+		t.Errorf("got token %q, wanted %q", token, "token")
+	}
+
+	// OktaOrgs of other namespaces cannot be referenced.
+	oktaClient.Namespace = "other"
+	_, err = r.oktaAPI(oktaClient, context.Background())
+	if err == nil {
+		t.Errorf("expected error referencing oktaOrg of another namespace")
+	}
+}
+
+func TestOktaAPIClusterOktaOrg(t *testing.T) {
+	t.Parallel()
+	oktaClient := testAppClient.DeepCopy()
+	oktaClient.Spec.OrgRef = &v1alpha1.OrgReference{Kind: v1alpha1.ClusterOktaOrgKind, Name: "production"}
+	org := &v1alpha1.ClusterOktaOrg{
+		ObjectMeta: metav1.ObjectMeta{Name: "production"},
+		Spec: v1alpha1.OktaOrgSpec{
+			OrgURL:               "https://production.example.com",
+			CredentialsSecretRef: &v1alpha1.CredentialsSecretReference{Name: "okta", Namespace: "okta", Key: "token"},
+		},
+	}
+	secret := &core.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "okta", Namespace: "okta"},
+		Data:       map[string][]byte{"OKTA_CLIENT_TOKEN": []byte("wrong")},
+	}
+
+	orgs := newOrgsMock()
+	kubernetesClient := newTestClient(org, secret)
+	r := &OktaClientReconciler{Client: kubernetesClient, Orgs: orgs}
+	_, err := r.oktaAPI(oktaClient, context.Background())
+	if err == nil {
+		t.Errorf("expected error using credentials secret without key %q", "token")
+	}
+
+	secret.Data["token"] = []byte("token")
+	err = kubernetesClient.Update(context.Background(), secret)
+	if err != nil {
+		t.Fatalf("error updating secret: %v", err)
+	}
+	oktaAPI, err := r.oktaAPI(oktaClient, context.Background())
+	if err != nil || oktaAPI != orgs.orgs["https://production.example.com"] {
+		t.Errorf("got API %v and error %v, wanted the production organization", oktaAPI, err)
+	}
+}
+
+func TestOktaAPIOAuth(t *testing.T) {
+	t.Parallel()
+	oktaClient := testAppClient.DeepCopy()
+	oktaClient.Spec.OrgRef = &v1alpha1.OrgReference{Name: "preview"}
+	org := &v1alpha1.OktaOrg{
+		ObjectMeta: metav1.ObjectMeta{Name: "preview", Namespace: "default"},
+		Spec: v1alpha1.OktaOrgSpec{
+			OrgURL: "https://preview.example.com",
+			OAuth: &v1alpha1.OAuthCredentials{
+				ClientID:            "client",
+				PrivateKeySecretRef: v1alpha1.PrivateKeySecretReference{Name: "okta-preview"},
+				PrivateKeyID:        "kid",
+			},
+		},
+	}
+	secret := &core.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "okta-preview", Namespace: "default"},
+		Data:       map[string][]byte{"OKTA_CLIENT_PRIVATEKEY": []byte("private key")},
+	}
+
+	orgs := newOrgsMock()
+	r := &OktaClientReconciler{Client: newTestClient(org, secret), Orgs: orgs}
+	_, err := r.oktaAPI(oktaClient, context.Background())
+	if err != nil {
+		t.Fatalf("error resolving Okta organization: %v", err)
+	}
+	credentials := orgs.credentials["https://preview.example.com"]
+	if credentials.ClientID != "client" || string(credentials.PrivateKey) != "private key" || credentials.PrivateKeyID != "kid" {
+		t.Errorf("got credentials %+v, wanted the service application", credentials)
+	}
+}
+
+func TestOktaAPIWithoutCredentials(t *testing.T) {
+	t.Parallel()
+	oktaClient := testAppClient.DeepCopy()
+	oktaClient.Spec.OrgRef = &v1alpha1.OrgReference{Name: "preview"}
+	org := &v1alpha1.OktaOrg{
+		ObjectMeta: metav1.ObjectMeta{Name: "preview", Namespace: "default"},
+		Spec:       v1alpha1.OktaOrgSpec{OrgURL: "https://preview.example.com"},
+	}
+
+	r := &OktaClientReconciler{Client: newTestClient(org), Orgs: newOrgsMock()}
+	_, err := r.oktaAPI(oktaClient, context.Background())
+	if err == nil {
+		t.Errorf("expected error using organization without credentials")
+	}
+}
+
+func TestCleanUpOrphanWithoutOrg(t *testing.T) {
+	t.Parallel()
+	oktaClient := testAppClient.DeepCopy()
+	oktaClient.Spec.OrgRef = &v1alpha1.OrgReference{Name: "deleted"}
+	oktaClient.Spec.DeletionPolicy = v1alpha1.DeletionPolicyOrphan
+
+	r := &OktaClientReconciler{Client: newTestClient(), Orgs: newOrgsMock()}
+	err := r.cleanUp(oktaClient, context.Background(), testRequest)
+	if err != nil {
+		t.Errorf("error cleaning up orphaned application of deleted organization: %v", err)
+	}
+
+	oktaClient.Spec.DeletionPolicy = v1alpha1.DeletionPolicyDelete
+	err = r.cleanUp(oktaClient, context.Background(), testRequest)
+	if err == nil {
+		t.Errorf("expected error deleting application of deleted organization")
+	}
+}
@@ -0,0 +1,197 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	oktav1alpha1 "github.com/jaconi-io/okta-operator/api/v1alpha1"
+	"github.com/jaconi-io/okta-operator/okta"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"net/http"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sync"
+	"time"
+)
+
+const probeBufferSize = 1024
+
+// errNotProbed is reported by the readiness check, until the Okta organization has been probed.
+var errNotProbed = errors.New("Okta organization has not been probed yet")
+
+// OktaProbe periodically calls the Okta API of the default organization to verify that it is reachable and accepts the
+// credentials of the operator. It serves as readiness check and keeps OktaClients of the default organization from
+// being reconciled, while the organization is unreachable. Once the organization becomes reachable or unreachable,
+// these OktaClients are reconciled, so their OktaUnreachable condition is updated.
+type OktaProbe struct {
+	okta     okta.API
+	reader   client.Reader
+	interval time.Duration
+	events   chan event.GenericEvent
+
+	mu          sync.RWMutex
+	probed      bool
+	lastSuccess time.Time
+	err         error
+}
+
+// NewOktaProbe returns a probe calling the given Okta API every interval. The reader lists the OktaClients to
+// reconcile, once the reachability of the organization changes.
+func NewOktaProbe(oktaAPI okta.API, reader client.Reader, interval time.Duration) *OktaProbe {
+	return &OktaProbe{
+		okta:     oktaAPI,
+		reader:   reader,
+		interval: interval,
+		events:   make(chan event.GenericEvent, probeBufferSize),
+	}
+}
+
+// Events returns the channel the OktaClients of the default organization are sent to, once its reachability changes.
+// See OktaClientReconciler.Probe.
+func (p *OktaProbe) Events() <-chan event.GenericEvent {
+	return p.events
+}
+
+// Start probes the Okta organization immediately and then every interval, until the context is done. It implements
+// manager.Runnable.
+func (p *OktaProbe) Start(ctx context.Context) error {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.probe(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection returns false, so all replicas report their readiness. It implements
+// manager.LeaderElectionRunnable.
+func (p *OktaProbe) NeedLeaderElection() bool {
+	return false
+}
+
+// probe calls the Okta API once and records the result. Requests taking longer than the interval are cancelled.
+func (p *OktaProbe) probe(ctx context.Context) {
+	log := ctrllog.FromContext(ctx).WithName("oktaProbe")
+
+	pingCtx, cancel := context.WithTimeout(ctx, p.interval)
+	defer cancel()
+	err := p.okta.Ping(pingCtx)
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil && !okta.IsUnreachable(err) {
+		// Okta answered, e.g. with a server error, or the rate limit of the endpoint is exhausted. Neither means the
+		// organization is down, so its OktaClients keep being reconciled.
+		log.V(1).Info("Okta organization is reachable, but the probe failed", "error", err.Error())
+		err = nil
+	}
+
+	p.mu.Lock()
+	changed := (p.err == nil) != (err == nil)
+	p.probed = true
+	p.err = err
+	if err == nil {
+		p.lastSuccess = time.Now()
+	}
+	p.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	if err != nil {
+		log.Error(err, "Okta organization became unreachable")
+	} else {
+		log.Info("Okta organization became reachable")
+	}
+
+	err = p.enqueueOktaClients(ctx)
+	if err != nil {
+		log.Error(err, "Failed to reconcile OktaClients of the Okta organization")
+	}
+}
+
+// enqueueOktaClients sends the OktaClients of the default organization to the events channel.
+func (p *OktaProbe) enqueueOktaClients(ctx context.Context) error {
+	oktaClients := &oktav1alpha1.OktaClientList{}
+	err := p.reader.List(ctx, oktaClients)
+	if err != nil {
+		return fmt.Errorf("failed to list oktaClients: %w", err)
+	}
+
+	for i := range oktaClients.Items {
+		oktaClient := &oktaClients.Items[i]
+		if oktaClient.Spec.OrgRef != nil {
+			continue
+		}
+		select {
+		case p.events <- event.GenericEvent{Object: oktaClient}:
+		default:
+			// Until its next resync, the OktaUnreachable condition of the OktaClient is stale. Readiness is reported
+			// by Check regardless.
+		}
+	}
+	return nil
+}
+
+// LastSuccess returns the time the Okta organization has last been reachable. It is zero, if it has never been.
+func (p *OktaProbe) LastSuccess() time.Time {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.lastSuccess
+}
+
+// Unreachable returns the error of the last probe, including the time of the last success. It is nil, if the
+// organization has been reachable or has not been probed yet.
+func (p *OktaProbe) Unreachable() error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	switch {
+	case p.err == nil:
+		return nil
+	case p.lastSuccess.IsZero():
+		return fmt.Errorf("Okta organization has never been reachable: %w", p.err)
+	default:
+		return fmt.Errorf("Okta organization has been unreachable since the last success at %s: %w", p.lastSuccess.UTC().Format(time.RFC3339), p.err)
+	}
+}
+
+// Check returns an error, if the Okta organization has not been reachable on the last probe or has not been probed
+// yet. It implements healthz.Checker.
+func (p *OktaProbe) Check(_ *http.Request) error {
+	p.mu.RLock()
+	probed := p.probed
+	p.mu.RUnlock()
+
+	if !probed {
+		return errNotProbed
+	}
+	return p.Unreachable()
+}
+
+// setUnreachableCondition sets the OktaUnreachable condition of the OktaClient. The condition is true, if an error is
+// given.
+func setUnreachableCondition(oktaClient *oktav1alpha1.OktaClient, unreachable error) {
+	condition := metav1.Condition{
+		Type:               ConditionTypeOktaUnreachable,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: oktaClient.Generation,
+		Reason:             ReasonOktaReachable,
+		Message:            "Okta organization is reachable",
+	}
+	if unreachable != nil {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = ReasonOktaUnreachable
+		condition.Message = unreachable.Error()
+	}
+	meta.SetStatusCondition(&oktaClient.Status.Conditions, condition)
+}
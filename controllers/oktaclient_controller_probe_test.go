@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"github.com/jaconi-io/okta-operator/api/v1alpha1"
+	"github.com/jaconi-io/okta-operator/okta"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/tools/record"
+	"net"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"testing"
+	"time"
+)
+
+var errNoSuchHost = fmt.Errorf("failed to reach Okta organization: %w",
+	&net.DNSError{Err: "no such host", Name: "example.okta.com", IsNotFound: true})
+
+func TestOktaProbe(t *testing.T) {
+	t.Parallel()
+	oktaAPI := newOktaMock()
+	otherOrg := testAppClient.DeepCopy()
+	otherOrg.Name = "other"
+	otherOrg.Spec.OrgRef = &v1alpha1.OrgReference{Name: "other"}
+	probe := NewOktaProbe(oktaAPI, newTestClient(testAppClient.DeepCopy(), otherOrg), time.Minute)
+
+	if err := probe.Check(nil); err == nil {
+		t.Errorf("expected check to fail before the first probe")
+	}
+
+	probe.probe(context.Background())
+	if err := probe.Check(nil); err != nil || probe.LastSuccess().IsZero() {
+		t.Errorf("got error %v and last success %v, wanted a reachable organization", err, probe.LastSuccess())
+	}
+	if len(probe.events) != 0 {
+		t.Errorf("got %d events, wanted none", len(probe.events))
+	}
+
+	// The rate limit of the probed endpoint is exhausted, but the organization is reachable.
+	oktaAPI.setPingErr(&okta.RateLimitError{Endpoint: "/api/v1/trustedOrigins", Reset: time.Now().Add(time.Minute)})
+	probe.probe(context.Background())
+	if err := probe.Check(nil); err != nil || len(probe.events) != 0 {
+		t.Errorf("got error %v and %d events, wanted a reachable organization", err, len(probe.events))
+	}
+
+	// The org URL cannot be resolved.
+	lastSuccess := probe.LastSuccess()
+	oktaAPI.setPingErr(errNoSuchHost)
+	probe.probe(context.Background())
+	if err := probe.Check(nil); err == nil || probe.Unreachable() == nil || probe.LastSuccess() != lastSuccess {
+		t.Errorf("got error %v and last success %v, wanted an unreachable organization", err, probe.LastSuccess())
+	}
+	if len(probe.events) != 1 || (<-probe.events).Object.GetName() != testAppClient.Name {
+		t.Errorf("wanted the oktaClient of the default organization to be reconciled")
+	}
+
+	// OktaClients are only reconciled, once the reachability changes.
+	probe.probe(context.Background())
+	if len(probe.events) != 0 {
+		t.Errorf("got %d events, wanted none", len(probe.events))
+	}
+
+	oktaAPI.setPingErr(nil)
+	probe.probe(context.Background())
+	if err := probe.Check(nil); err != nil || len(probe.events) != 1 {
+		t.Errorf("got error %v and %d events, wanted a reachable organization", err, len(probe.events))
+	}
+}
+
+func TestReconcileOktaUnreachable(t *testing.T) {
+	t.Parallel()
+	oktaClient := testAppClient.DeepCopy()
+	oktaAPI := newOktaMock()
+	oktaAPI.setPingErr(errNoSuchHost)
+	kubernetesClient := newTestClient(oktaClient, newTestSecret(nil))
+	probe := NewOktaProbe(oktaAPI, kubernetesClient, time.Minute)
+	probe.probe(context.Background())
+
+	r := &OktaClientReconciler{
+		Client:   kubernetesClient,
+		Recorder: record.NewFakeRecorder(10),
+		Okta:     oktaAPI,
+		Probe:    probe,
+	}
+	result, err := r.Reconcile(context.Background(), testRequest)
+	if err != nil || result.RequeueAfter != time.Minute {
+		t.Fatalf("got result %+v and error %v, wanted requeue after the probe interval", result, err)
+	}
+
+	err = r.Get(context.Background(), testRequest.NamespacedName, oktaClient)
+	if err != nil {
+		t.Fatalf("error getting oktaClient: %v", err)
+	}
+	if !meta.IsStatusConditionTrue(oktaClient.Status.Conditions, ConditionTypeOktaUnreachable) {
+		t.Errorf("got condition %q false, wanted true", ConditionTypeOktaUnreachable)
+	}
+	if oktaAPI.appsCreated != 0 {
+		t.Errorf("got %d applications created, wanted none", oktaAPI.appsCreated)
+	}
+
+	oktaAPI.setPingErr(nil)
+	probe.probe(context.Background())
+	_, err = r.Reconcile(context.Background(), testRequest)
+	if err != nil {
+		t.Fatalf("error reconciling oktaClient: %v", err)
+	}
+
+	err = r.Get(context.Background(), testRequest.NamespacedName, oktaClient)
+	if err != nil {
+		t.Fatalf("error getting oktaClient: %v", err)
+	}
+	if !meta.IsStatusConditionFalse(oktaClient.Status.Conditions, ConditionTypeOktaUnreachable) {
+		t.Errorf("got condition %q true, wanted false", ConditionTypeOktaUnreachable)
+	}
+	if !meta.IsStatusConditionTrue(oktaClient.Status.Conditions, ConditionTypeSynced) || oktaAPI.appsCreated != 1 {
+		t.Errorf("got conditions %+v, wanted a synced oktaClient", oktaClient.Status.Conditions)
+	}
+}
+
+func TestReconcileOktaUnreachableStatusError(t *testing.T) {
+	t.Parallel()
+	oktaAPI := newOktaMock()
+	oktaAPI.setPingErr(errNoSuchHost)
+	kubernetesClient := interceptor.NewClient(newTestClient(testAppClient.DeepCopy()).(client.WithWatch), interceptor.Funcs{
+		SubResourceUpdate: func(context.Context, client.Client, string, client.Object, ...client.SubResourceUpdateOption) error {
+			return fmt.Errorf("conflict")
+		},
+	})
+	probe := NewOktaProbe(oktaAPI, kubernetesClient, time.Minute)
+	probe.probe(context.Background())
+
+	r := &OktaClientReconciler{
+		Client:   kubernetesClient,
+		Recorder: record.NewFakeRecorder(10),
+		Okta:     oktaAPI,
+		Probe:    probe,
+	}
+	_, err := r.Reconcile(context.Background(), testRequest)
+	if err == nil {
+		t.Errorf("got no error, wanted the error updating the status")
+	}
+}
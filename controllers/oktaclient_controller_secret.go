@@ -0,0 +1,327 @@
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	oktav1alpha1 "github.com/jaconi-io/okta-operator/api/v1alpha1"
+	"github.com/jaconi-io/okta-operator/okta"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+const (
+	secretKeyClientID              = "OKTA_CLIENT_ID"
+	secretKeyClientSecret          = "OKTA_CLIENT_SECRET"
+	secretKeyOrgURL                = "OKTA_ORG_URL"
+	secretKeyIssuer                = "OKTA_ISSUER"
+	secretKeyDiscoveryURL          = "OKTA_DISCOVERY_URL"
+	secretKeyAuthorizationEndpoint = "OKTA_AUTHORIZATION_ENDPOINT"
+	secretKeyTokenEndpoint         = "OKTA_TOKEN_ENDPOINT"
+	secretKeyUserinfoEndpoint      = "OKTA_USERINFO_ENDPOINT"
+	secretKeyJWKSURI               = "OKTA_JWKS_URI"
+	secretKeyEndSessionEndpoint    = "OKTA_END_SESSION_ENDPOINT"
+
+	// The annotations record what the secret template manages in the secret, so it can be undone, once the template
+	// changes: the renamed default keys (e.g. "OKTA_CLIENT_ID=clientId"), the templated keys, the labels and the
+	// annotations.
+	annotationSecretKeys        = "okta.jaconi.io/secret-keys"
+	annotationSecretData        = "okta.jaconi.io/secret-data"
+	annotationSecretLabels      = "okta.jaconi.io/secret-labels"
+	annotationSecretAnnotations = "okta.jaconi.io/secret-annotations"
+)
+
+// credentialSecretKeys are the default keys holding credentials. They are read back from the secret, so they can be
+// renamed, but neither omitted nor replaced by a template.
+var credentialSecretKeys = []string{secretKeyClientSecret, secretKeyPrivateKey}
+
+// secretValues are the data model of the templates in the secret template of an OktaClient.
+type secretValues struct {
+	ApplicationID          string
+	ClientID               string
+	ClientSecret           string
+	PrivateKey             string
+	KeyID                  string
+	OrgURL                 string
+	Issuer                 string
+	DiscoveryURL           string
+	AuthorizationEndpoint  string
+	TokenEndpoint          string
+	UserinfoEndpoint       string
+	JWKSURI                string
+	EndSessionEndpoint     string
+	RedirectURIs           []string
+	PostLogoutRedirectURIs []string
+}
+
+// newSecretValues collects the values stored in the secret of an OktaClient. Credentials are only known right after they
+// have been created. Otherwise, the credentials already stored in the secret are used.
+func newSecretValues(oktaClient *oktav1alpha1.OktaClient, app *okta.Application, orgURL string, metadata *okta.AuthorizationServerMetadata, secret *core.Secret, privateKey []byte) secretValues {
+	settings := applicationSettings(oktaClient)
+	values := secretValues{
+		ApplicationID:          app.ID,
+		ClientID:               app.ClientID,
+		OrgURL:                 orgURL,
+		Issuer:                 metadata.Issuer,
+		DiscoveryURL:           metadata.DiscoveryURL,
+		AuthorizationEndpoint:  metadata.AuthorizationEndpoint,
+		TokenEndpoint:          metadata.TokenEndpoint,
+		UserinfoEndpoint:       metadata.UserinfoEndpoint,
+		JWKSURI:                metadata.JWKSURI,
+		EndSessionEndpoint:     metadata.EndSessionEndpoint,
+		RedirectURIs:           settings.RedirectUris,
+		PostLogoutRedirectURIs: settings.PostLogoutRedirectUris,
+	}
+
+	if hasClientSecret(settings) {
+		values.ClientSecret = string(secretCredential(secret, secretKeyClientSecret))
+		if app.ClientSecret != "" {
+			values.ClientSecret = app.ClientSecret
+		}
+	}
+
+	if settings.TokenEndpointAuthMethod == "private_key_jwt" {
+		values.PrivateKey = string(secretCredential(secret, secretKeyPrivateKey))
+		if privateKey != nil {
+			values.PrivateKey = string(privateKey)
+		}
+		if oktaClient.Status.PrivateKey != nil {
+			values.KeyID = oktaClient.Status.PrivateKey.KeyID
+		}
+	}
+
+	return values
+}
+
+// renderSecret sets the data, labels and annotations of the secret of an OktaClient. The default keys are renamed,
+// omitted or replaced as configured by the secret template. Keys, labels and annotations the template no longer
+// configures are removed. Everything else, e.g. keys added to a secret handed in by the user, is left alone.
+func renderSecret(oktaClient *oktav1alpha1.OktaClient, secret *core.Secret, values secretValues) error {
+	secretTemplate := oktaClient.Spec.SecretTemplate
+	if secretTemplate == nil {
+		secretTemplate = &oktav1alpha1.SecretTemplate{}
+	}
+
+	data := map[string][]byte{}
+	var renamed []string
+	for defaultKey, value := range defaultSecretData(values) {
+		key := secretKey(secretTemplate, defaultKey)
+		if key != defaultKey && key != "" {
+			renamed = append(renamed, defaultKey+"="+key)
+		}
+		if key != "" && value != "" {
+			data[key] = []byte(value)
+		}
+	}
+
+	var templated []string
+	for key, text := range secretTemplate.Data {
+		templated = append(templated, key)
+		tmpl, err := parseSecretTemplate(key, text)
+		if err != nil {
+			return err
+		}
+
+		var value bytes.Buffer
+		err = tmpl.Execute(&value, values)
+		if err != nil {
+			return fmt.Errorf("failed to render secret key %q: %w", key, err)
+		}
+		data[key] = value.Bytes()
+	}
+
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	for _, key := range managedSecretKeys(secret) {
+		delete(secret.Data, key)
+	}
+	for key, value := range data {
+		secret.Data[key] = value
+	}
+
+	managedLabels := secret.Annotations[annotationSecretLabels]
+	managedAnnotations := secret.Annotations[annotationSecretAnnotations]
+	secret.Labels, managedLabels = applyManaged(secret.Labels, managedLabels, secretTemplate.Labels)
+	secret.Annotations, managedAnnotations = applyManaged(secret.Annotations, managedAnnotations, secretTemplate.Annotations)
+
+	sort.Strings(renamed)
+	sort.Strings(templated)
+	secret.Annotations = setAnnotation(secret.Annotations, annotationSecretKeys, strings.Join(renamed, ","))
+	secret.Annotations = setAnnotation(secret.Annotations, annotationSecretData, strings.Join(templated, ","))
+	secret.Annotations = setAnnotation(secret.Annotations, annotationSecretLabels, managedLabels)
+	secret.Annotations = setAnnotation(secret.Annotations, annotationSecretAnnotations, managedAnnotations)
+
+	secret.StringData = nil
+	return nil
+}
+
+// managedSecretKeys returns the keys of the secret managed by the operator, according to the secret template it has
+// been rendered with last: the default keys, the keys they have been renamed to and the templated keys.
+func managedSecretKeys(secret *core.Secret) []string {
+	var keys []string
+	for defaultKey := range defaultSecretData(secretValues{}) {
+		keys = append(keys, defaultKey)
+	}
+	for _, rename := range strings.Split(secret.Annotations[annotationSecretKeys], ",") {
+		if _, key, ok := strings.Cut(rename, "="); ok {
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range strings.Split(secret.Annotations[annotationSecretData], ",") {
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// applyManaged sets the given entries and removes the entries previously managed, but no longer given. It returns the
+// updated entries and the comma separated keys now managed.
+func applyManaged(entries map[string]string, managed string, given map[string]string) (map[string]string, string) {
+	for _, key := range strings.Split(managed, ",") {
+		if _, ok := given[key]; !ok {
+			delete(entries, key)
+		}
+	}
+
+	keys := make([]string, 0, len(given))
+	for key, value := range given {
+		if entries == nil {
+			entries = map[string]string{}
+		}
+		entries[key] = value
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return entries, strings.Join(keys, ",")
+}
+
+// setAnnotation sets the annotation or removes it, if the value is empty.
+func setAnnotation(annotations map[string]string, key, value string) map[string]string {
+	if value == "" {
+		delete(annotations, key)
+		return annotations
+	}
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[key] = value
+	return annotations
+}
+
+// secretKey returns the key the default key is stored under according to the secret template. It is empty, if the
+// default key is omitted.
+func secretKey(secretTemplate *oktav1alpha1.SecretTemplate, defaultKey string) string {
+	if secretTemplate != nil {
+		if key, renamed := secretTemplate.Keys[defaultKey]; renamed {
+			return key
+		}
+	}
+	return defaultKey
+}
+
+// secretCredential returns the credential stored under the default key in the secret. The key may have been renamed
+// by the secret template the secret has been rendered with.
+func secretCredential(secret *core.Secret, defaultKey string) []byte {
+	if secret == nil {
+		return nil
+	}
+
+	key := defaultKey
+	for _, rename := range strings.Split(secret.Annotations[annotationSecretKeys], ",") {
+		from, to, _ := strings.Cut(rename, "=")
+		if from == defaultKey && to != "" {
+			key = to
+		}
+	}
+	return secret.Data[key]
+}
+
+// defaultSecretData maps the default keys of the secret to their values.
+func defaultSecretData(values secretValues) map[string]string {
+	return map[string]string{
+		secretKeyClientID:              values.ClientID,
+		secretKeyClientSecret:          values.ClientSecret,
+		secretKeyPrivateKey:            values.PrivateKey,
+		secretKeyKeyID:                 values.KeyID,
+		secretKeyOrgURL:                values.OrgURL,
+		secretKeyIssuer:                values.Issuer,
+		secretKeyDiscoveryURL:          values.DiscoveryURL,
+		secretKeyAuthorizationEndpoint: values.AuthorizationEndpoint,
+		secretKeyTokenEndpoint:         values.TokenEndpoint,
+		secretKeyUserinfoEndpoint:      values.UserinfoEndpoint,
+		secretKeyJWKSURI:               values.JWKSURI,
+		secretKeyEndSessionEndpoint:    values.EndSessionEndpoint,
+	}
+}
+
+// validateSecretTemplate returns an error, if a key of the secret template is invalid, collides with the key of a
+// credential or if its template cannot be parsed. Default keys must be renamed to distinct keys and credentials must
+// not be omitted.
+func validateSecretTemplate(secretTemplate *oktav1alpha1.SecretTemplate) error {
+	if secretTemplate == nil {
+		return nil
+	}
+
+	var defaultKeys []string
+	for defaultKey := range defaultSecretData(secretValues{}) {
+		defaultKeys = append(defaultKeys, defaultKey)
+	}
+	sort.Strings(defaultKeys)
+
+	keys := map[string]string{}
+	for _, defaultKey := range defaultKeys {
+		key := secretKey(secretTemplate, defaultKey)
+		if key == "" {
+			if slices.Contains(credentialSecretKeys, defaultKey) {
+				return fmt.Errorf("secret key %q cannot be omitted", defaultKey)
+			}
+			continue
+		}
+		if errs := validation.IsConfigMapKey(key); len(errs) > 0 {
+			return fmt.Errorf("invalid secret key %q: %s", key, strings.Join(errs, ", "))
+		}
+		if other, ok := keys[key]; ok {
+			return fmt.Errorf("secret keys %q and %q are both stored as %q", other, defaultKey, key)
+		}
+		keys[key] = defaultKey
+	}
+
+	for defaultKey := range secretTemplate.Keys {
+		if !slices.Contains(defaultKeys, defaultKey) {
+			return fmt.Errorf("secret key %q is not a default key", defaultKey)
+		}
+	}
+
+	for key, text := range secretTemplate.Data {
+		if errs := validation.IsConfigMapKey(key); len(errs) > 0 {
+			return fmt.Errorf("invalid secret key %q: %s", key, strings.Join(errs, ", "))
+		}
+
+		if defaultKey := keys[key]; slices.Contains(credentialSecretKeys, defaultKey) {
+			return fmt.Errorf("secret key %q is reserved for %s", key, defaultKey)
+		}
+
+		_, err := parseSecretTemplate(key, text)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseSecretTemplate parses the template of a secret key.
+func parseSecretTemplate(key, text string) (*template.Template, error) {
+	tmpl, err := template.New(key).Option("missingkey=error").Funcs(template.FuncMap{
+		"join": strings.Join,
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template of secret key %q: %w", key, err)
+	}
+	return tmpl, nil
+}
@@ -0,0 +1,250 @@
+package controllers
+
+import (
+	"context"
+	"github.com/jaconi-io/okta-operator/api/v1alpha1"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"maps"
+	"testing"
+)
+
+var testTemplateClient = v1alpha1.OktaClient{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:      "test-client",
+		Namespace: "default",
+	},
+	Spec: v1alpha1.OktaClientSpec{
+		Name:         "test-client",
+		RedirectUris: []string{"https://a.example.com/callback", "https://b.example.com/callback"},
+		SecretTemplate: &v1alpha1.SecretTemplate{
+			Data: map[string]string{
+				"SPRING_SECURITY_OAUTH2_CLIENT_REGISTRATION_OKTA_CLIENT_ID": "{{ .ClientID }}",
+				"OAUTH2_PROXY_OIDC_ISSUER_URL":                              "{{ .Issuer }}",
+				"REDIRECT_URIS":                                             `{{ join .RedirectURIs "," }}`,
+			},
+			Labels:      map[string]string{"app": "test"},
+			Annotations: map[string]string{"reloader.stakater.com/match": "true"},
+		},
+	},
+}
+
+func TestRenderSecret(t *testing.T) {
+	t.Parallel()
+	oktaAPI := newOktaMock()
+	secret := &core.Secret{Data: map[string][]byte{"OKTA_CLIENT_SECRET": []byte("stored"), "OTHER": []byte("other")}}
+
+	metadata, _ := oktaAPI.GetAuthorizationServerMetadata(context.Background(), "")
+	values := newSecretValues(&testTemplateClient, &testApp, oktaAPI.OrgURL(), metadata, secret, nil)
+	err := renderSecret(&testTemplateClient, secret, values)
+	if err != nil {
+		t.Fatalf("error rendering secret: %v", err)
+	}
+
+	want := map[string]string{
+		"OKTA_CLIENT_ID":              "id",
+		"OKTA_CLIENT_SECRET":          "secret",
+		"OKTA_ORG_URL":                "https://example.okta.com",
+		"OKTA_ISSUER":                 "https://example.okta.com",
+		"OKTA_DISCOVERY_URL":          "https://example.okta.com/.well-known/openid-configuration",
+		"OKTA_AUTHORIZATION_ENDPOINT": "https://example.okta.com/v1/authorize",
+		"OKTA_TOKEN_ENDPOINT":         "https://example.okta.com/v1/token",
+		"OKTA_JWKS_URI":               "https://example.okta.com/v1/keys",
+		"SPRING_SECURITY_OAUTH2_CLIENT_REGISTRATION_OKTA_CLIENT_ID": "id",
+		"OAUTH2_PROXY_OIDC_ISSUER_URL":                              "https://example.okta.com",
+		"REDIRECT_URIS":                                             "https://a.example.com/callback,https://b.example.com/callback",
+		"OTHER":                                                     "other",
+	}
+	if len(secret.Data) != len(want) {
+		t.Errorf("got %d keys, wanted %d", len(secret.Data), len(want))
+	}
+	for key, value := range want {
+		if string(secret.Data[key]) != value {
+			t.Errorf("got %q for key %q, wanted %q", secret.Data[key], key, value)
+		}
+	}
+	if secret.Labels["app"] != "test" || secret.Annotations["reloader.stakater.com/match"] != "true" {
+		t.Errorf("got labels %v and annotations %v, wanted those of the template", secret.Labels, secret.Annotations)
+	}
+}
+
+func TestRenderSecretKeepsStoredCredentials(t *testing.T) {
+	t.Parallel()
+	oktaAPI := newOktaMock()
+	secret := &core.Secret{Data: map[string][]byte{"OKTA_CLIENT_SECRET": []byte("stored")}}
+	app := testApp
+	app.ClientSecret = "" // The client secret is only known, when it is created.
+
+	metadata, _ := oktaAPI.GetAuthorizationServerMetadata(context.Background(), "")
+	err := renderSecret(&testTemplateClient, secret, newSecretValues(&testTemplateClient, &app, oktaAPI.OrgURL(), metadata, secret, nil))
+	if err != nil {
+		t.Fatalf("error rendering secret: %v", err)
+	}
+	if string(secret.Data["OKTA_CLIENT_SECRET"]) != "stored" {
+		t.Errorf("got client secret %q, wanted %q", secret.Data["OKTA_CLIENT_SECRET"], "stored")
+	}
+}
+
+func TestValidateSecretTemplate(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		data  map[string]string
+		keys  map[string]string
+		valid bool
+	}{
+		{data: map[string]string{"CLIENT_ID": "{{ .ClientID }}"}, valid: true},
+		{data: map[string]string{"client.properties": "client-id={{ .ClientID }}"}, valid: true},
+		{data: map[string]string{"CLIENT ID": "{{ .ClientID }}"}, valid: false},
+		{data: map[string]string{"OKTA_CLIENT_ID": "{{ .ClientID }}"}, valid: true},
+		{data: map[string]string{"OKTA_ISSUER": "{{ .Issuer }}/custom"}, valid: true},
+		{data: map[string]string{"OKTA_CLIENT_SECRET": "{{ .ClientSecret }}"}, valid: false},
+		{data: map[string]string{"CLIENT_ID": "{{ .ClientID "}, valid: false},
+		{keys: map[string]string{"OKTA_CLIENT_ID": "clientId", "OKTA_ISSUER": ""}, valid: true},
+		{keys: map[string]string{"OKTA_CLIENT_SECRET": "clientSecret"}, valid: true},
+		{keys: map[string]string{"OKTA_CLIENT_SECRET": ""}, valid: false},
+		{keys: map[string]string{"OKTA_CLIENT_PRIVATE_KEY": ""}, valid: false},
+		{keys: map[string]string{"OKTA_CLIENT_ID": "client id"}, valid: false},
+		{keys: map[string]string{"OKTA_CLIENT_ID": "OKTA_ISSUER"}, valid: false},
+		{keys: map[string]string{"OKTA_CLIENT": "clientId"}, valid: false},
+		{
+			data:  map[string]string{"clientSecret": "{{ .ClientSecret }}"},
+			keys:  map[string]string{"OKTA_CLIENT_SECRET": "clientSecret"},
+			valid: false,
+		},
+		{
+			data:  map[string]string{"OKTA_CLIENT_SECRET": "{{ .ClientID }}"},
+			keys:  map[string]string{"OKTA_CLIENT_SECRET": "clientSecret"},
+			valid: true,
+		},
+	} {
+		err := validateSecretTemplate(&v1alpha1.SecretTemplate{Data: tc.data, Keys: tc.keys})
+		if (err == nil) != tc.valid {
+			t.Errorf("got error %v for %v and %v, wanted valid=%t", err, tc.data, tc.keys, tc.valid)
+		}
+	}
+}
+
+func TestRenderSecretKeys(t *testing.T) {
+	t.Parallel()
+	oktaAPI := newOktaMock()
+	oktaClient := testAppClient.DeepCopy()
+	oktaClient.Spec.SecretTemplate = &v1alpha1.SecretTemplate{
+		Data: map[string]string{"OKTA_ISSUER": "{{ .Issuer }}/custom"},
+		Keys: map[string]string{"OKTA_CLIENT_ID": "clientId", "OKTA_CLIENT_SECRET": "clientSecret", "OKTA_ORG_URL": ""},
+	}
+	secret := &core.Secret{}
+	metadata, _ := oktaAPI.GetAuthorizationServerMetadata(context.Background(), "")
+
+	err := renderSecret(oktaClient, secret, newSecretValues(oktaClient, &testApp, oktaAPI.OrgURL(), metadata, secret, nil))
+	if err != nil {
+		t.Fatalf("error rendering secret: %v", err)
+	}
+	for key, value := range map[string]string{
+		"clientId":     "id",
+		"clientSecret": "secret",
+		"OKTA_ISSUER":  "https://example.okta.com/custom",
+	} {
+		if string(secret.Data[key]) != value {
+			t.Errorf("got %q for key %q, wanted %q", secret.Data[key], key, value)
+		}
+	}
+	for _, key := range []string{"OKTA_CLIENT_ID", "OKTA_CLIENT_SECRET", "OKTA_ORG_URL"} {
+		if _, ok := secret.Data[key]; ok {
+			t.Errorf("got key %q, wanted it to be renamed or omitted", key)
+		}
+	}
+
+	// The stored client secret is found, once the key is renamed again.
+	oktaClient.Spec.SecretTemplate.Keys["OKTA_CLIENT_SECRET"] = "client-secret"
+	app := testApp
+	app.ClientSecret = ""
+	err = renderSecret(oktaClient, secret, newSecretValues(oktaClient, &app, oktaAPI.OrgURL(), metadata, secret, nil))
+	if err != nil {
+		t.Fatalf("error rendering secret: %v", err)
+	}
+	if string(secret.Data["client-secret"]) != "secret" {
+		t.Errorf("got client secret %q, wanted %q", secret.Data["client-secret"], "secret")
+	}
+}
+
+func TestRenderSecretKeepsUnmanagedKeys(t *testing.T) {
+	t.Parallel()
+	oktaAPI := newOktaMock()
+	oktaClient := testTemplateClient.DeepCopy()
+	secret := &core.Secret{Data: map[string][]byte{"USER_KEY": []byte("user")}}
+	metadata, _ := oktaAPI.GetAuthorizationServerMetadata(context.Background(), "")
+
+	err := renderSecret(oktaClient, secret, newSecretValues(oktaClient, &testApp, oktaAPI.OrgURL(), metadata, secret, nil))
+	if err != nil {
+		t.Fatalf("error rendering secret: %v", err)
+	}
+
+	// Keys removed from the template and omitted default keys are removed, the key of the user is kept.
+	oktaClient.Spec.SecretTemplate.Data = map[string]string{"CLIENT_ID": "{{ .ClientID }}"}
+	oktaClient.Spec.SecretTemplate.Keys = map[string]string{"OKTA_ORG_URL": ""}
+	err = renderSecret(oktaClient, secret, newSecretValues(oktaClient, &testApp, oktaAPI.OrgURL(), metadata, secret, nil))
+	if err != nil {
+		t.Fatalf("error rendering secret: %v", err)
+	}
+	for _, key := range []string{"REDIRECT_URIS", "OAUTH2_PROXY_OIDC_ISSUER_URL", "OKTA_ORG_URL"} {
+		if _, ok := secret.Data[key]; ok {
+			t.Errorf("got key %q, wanted it to be removed", key)
+		}
+	}
+	if string(secret.Data["USER_KEY"]) != "user" || string(secret.Data["CLIENT_ID"]) != "id" {
+		t.Errorf("got data %v, wanted the key of the user and the templated key", secret.Data)
+	}
+}
+
+func TestRenderSecretRemovesUnmanaged(t *testing.T) {
+	t.Parallel()
+	oktaAPI := newOktaMock()
+	oktaClient := testTemplateClient.DeepCopy()
+	secret := &core.Secret{ObjectMeta: metav1.ObjectMeta{
+		Labels:      map[string]string{"other": "label"},
+		Annotations: map[string]string{"other": "annotation"},
+	}}
+	metadata, _ := oktaAPI.GetAuthorizationServerMetadata(context.Background(), "")
+
+	err := renderSecret(oktaClient, secret, newSecretValues(oktaClient, &testApp, oktaAPI.OrgURL(), metadata, secret, nil))
+	if err != nil {
+		t.Fatalf("error rendering secret: %v", err)
+	}
+
+	// Labels and annotations removed from the template are removed from the secret, others are kept.
+	oktaClient.Spec.SecretTemplate.Labels = map[string]string{"team": "test"}
+	oktaClient.Spec.SecretTemplate.Annotations = nil
+	err = renderSecret(oktaClient, secret, newSecretValues(oktaClient, &testApp, oktaAPI.OrgURL(), metadata, secret, nil))
+	if err != nil {
+		t.Fatalf("error rendering secret: %v", err)
+	}
+	wantLabels := map[string]string{"other": "label", "team": "test"}
+	if !maps.Equal(secret.Labels, wantLabels) {
+		t.Errorf("got labels %v, wanted %v", secret.Labels, wantLabels)
+	}
+
+	oktaClient.Spec.SecretTemplate = nil
+	err = renderSecret(oktaClient, secret, newSecretValues(oktaClient, &testApp, oktaAPI.OrgURL(), metadata, secret, nil))
+	if err != nil {
+		t.Fatalf("error rendering secret: %v", err)
+	}
+	wantLabels = map[string]string{"other": "label"}
+	wantAnnotations := map[string]string{"other": "annotation"}
+	if !maps.Equal(secret.Labels, wantLabels) || !maps.Equal(secret.Annotations, wantAnnotations) {
+		t.Errorf("got labels %v and annotations %v, wanted %v and %v", secret.Labels, secret.Annotations, wantLabels, wantAnnotations)
+	}
+}
+
+func TestUpdateApplicationCustomAuthorizationServer(t *testing.T) {
+	t.Parallel()
+	oktaClient := testAppClient.DeepCopy()
+	oktaClient.Spec.AuthorizationServerID = "default"
+
+	err := updateApplication(oktaClient, context.Background(), testRequest, newTestClient(), newOktaMock())
+	if err != nil {
+		t.Errorf("error updating application: %v", err)
+	}
+	if oktaClient.Status.Issuer != "https://example.okta.com/oauth2/default" {
+		t.Errorf("got issuer %q, wanted %q", oktaClient.Status.Issuer, "https://example.okta.com/oauth2/default")
+	}
+}
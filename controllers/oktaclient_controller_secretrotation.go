@@ -0,0 +1,190 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	oktav1alpha1 "github.com/jaconi-io/okta-operator/api/v1alpha1"
+	"github.com/jaconi-io/okta-operator/okta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"time"
+)
+
+const defaultSecretGracePeriod = 24 * time.Hour
+
+// rotateClientSecret performs the scheduled rotation of the client secret of an OktaClient. Once its grace period has
+// passed, the previous client secret is deactivated and deleted. If the current client secret is due for rotation, a
+// new client secret is created. The new client secret is returned together with the status to record, once it has been
+// stored in the Kubernetes secret.
+func rotateClientSecret(oktaClient *oktav1alpha1.OktaClient, ctx context.Context, app *okta.Application, now time.Time, oktaAPI okta.API) (*okta.ClientSecret, *oktav1alpha1.SecretRotationStatus, error) {
+	rotation := oktaClient.Spec.SecretRotation
+	status := oktaClient.Status.SecretRotation
+	if status == nil {
+		if rotation == nil {
+			return nil, nil, nil
+		}
+
+		// Start with the newest active client secret.
+		secrets, err := oktaAPI.ListClientSecrets(ctx, app)
+		if err != nil {
+			return nil, nil, err
+		}
+		status = &oktav1alpha1.SecretRotationStatus{RotatedAt: &metav1.Time{Time: now}}
+		newest := newestClientSecret(secrets)
+		if newest != nil {
+			status.SecretID = newest.ID
+			if !newest.Created.IsZero() {
+				status.RotatedAt = &metav1.Time{Time: newest.Created}
+			}
+		}
+		oktaClient.Status.SecretRotation = status
+	}
+
+	if status.PreviousSecretID != "" && (status.PreviousSecretExpiresAt == nil || !now.Before(status.PreviousSecretExpiresAt.Time)) {
+		err := oktaAPI.DeleteClientSecret(ctx, app, status.PreviousSecretID)
+		if err != nil {
+			return nil, nil, err
+		}
+		status.PreviousSecretID = ""
+		status.PreviousSecretExpiresAt = nil
+	}
+
+	if rotation == nil || status.PreviousSecretID != "" || !secretRotationDue(status, rotation.Interval.Duration, now) {
+		return nil, nil, nil
+	}
+
+	// Okta supports up to two client secrets per application. Delete client secrets left over by failed rotations.
+	if status.SecretID != "" {
+		secrets, err := oktaAPI.ListClientSecrets(ctx, app)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, secret := range secrets {
+			if secret.ID != status.SecretID {
+				err = oktaAPI.DeleteClientSecret(ctx, app, secret.ID)
+				if err != nil {
+					return nil, nil, err
+				}
+			}
+		}
+	}
+
+	secret, err := oktaAPI.CreateClientSecret(ctx, app)
+	if err != nil {
+		return nil, nil, err
+	}
+	if secret.Secret == "" {
+		return nil, nil, fmt.Errorf("client secret %q of application %q was created without a value", secret.ID, app.ID)
+	}
+
+	rotated := &oktav1alpha1.SecretRotationStatus{
+		SecretID:  secret.ID,
+		RotatedAt: &metav1.Time{Time: now},
+	}
+	if status.SecretID != "" {
+		gracePeriod := defaultSecretGracePeriod
+		if rotation.GracePeriod != nil {
+			gracePeriod = rotation.GracePeriod.Duration
+		}
+		rotated.PreviousSecretID = status.SecretID
+		rotated.PreviousSecretExpiresAt = &metav1.Time{Time: now.Add(gracePeriod)}
+	}
+
+	return secret, rotated, nil
+}
+
+// rotateClientSecretOnDemand rotates the client secret of an OktaClient, if requested by the rotate-secret annotation.
+// In contrast to scheduled rotations, the previous client secret expires right away, once the new client secret has
+// been stored in the Kubernetes secret. The new client secret is returned together with the status to record.
+func rotateClientSecretOnDemand(oktaClient *oktav1alpha1.OktaClient, ctx context.Context, app *okta.Application, now time.Time, oktaAPI okta.API) (*okta.ClientSecret, *oktav1alpha1.SecretRotationStatus, error) {
+	secrets, err := oktaAPI.ListClientSecrets(ctx, app)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	current := newestClientSecret(secrets)
+	if status := oktaClient.Status.SecretRotation; status != nil && status.SecretID != "" {
+		for i := range secrets {
+			if secrets[i].ID == status.SecretID {
+				current = &secrets[i]
+			}
+		}
+	}
+
+	// Okta supports up to two client secrets per application. Only keep the current one.
+	for _, secret := range secrets {
+		if current == nil || secret.ID != current.ID {
+			err = oktaAPI.DeleteClientSecret(ctx, app, secret.ID)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	secret, err := oktaAPI.CreateClientSecret(ctx, app)
+	if err != nil {
+		return nil, nil, err
+	}
+	if secret.Secret == "" {
+		return nil, nil, fmt.Errorf("client secret %q of application %q was created without a value", secret.ID, app.ID)
+	}
+
+	rotated := &oktav1alpha1.SecretRotationStatus{
+		SecretID:  secret.ID,
+		RotatedAt: &metav1.Time{Time: now},
+	}
+	if current != nil {
+		rotated.PreviousSecretID = current.ID
+		rotated.PreviousSecretExpiresAt = &metav1.Time{Time: now}
+	}
+
+	return secret, rotated, nil
+}
+
+// secretRotationRequested returns the value of the rotate-secret annotation, if it has not been handled yet.
+func secretRotationRequested(oktaClient *oktav1alpha1.OktaClient) string {
+	requested := oktaClient.GetAnnotations()[annotationRotateSecret]
+	if requested == oktaClient.Status.LastSecretRotationRequest {
+		return ""
+	}
+	return requested
+}
+
+// newestClientSecret returns the newest active client secret or nil, if there is none.
+func newestClientSecret(secrets []okta.ClientSecret) *okta.ClientSecret {
+	var newest *okta.ClientSecret
+	for i, secret := range secrets {
+		if secret.Status == "ACTIVE" && (newest == nil || secret.Created.After(newest.Created)) {
+			newest = &secrets[i]
+		}
+	}
+	return newest
+}
+
+// nextSecretRotation returns the duration until the client secret of the OktaClient has to be reconciled again, either
+// because it is due for rotation or because the grace period of the previous client secret ends. Zero is returned, if
+// no such event is pending.
+func nextSecretRotation(oktaClient *oktav1alpha1.OktaClient, now time.Time) time.Duration {
+	status := oktaClient.Status.SecretRotation
+	if status == nil {
+		return 0
+	}
+
+	// Events that are already due are handled right away.
+	var next time.Duration
+	if oktaClient.Spec.SecretRotation != nil && status.RotatedAt != nil {
+		next = max(status.RotatedAt.Add(oktaClient.Spec.SecretRotation.Interval.Duration).Sub(now), time.Second)
+	}
+	if status.PreviousSecretExpiresAt != nil {
+		expires := max(status.PreviousSecretExpiresAt.Sub(now), time.Second)
+		if next == 0 || expires < next {
+			next = expires
+		}
+	}
+
+	return next
+}
+
+// secretRotationDue returns true, if the current client secret is older than the rotation interval.
+func secretRotationDue(status *oktav1alpha1.SecretRotationStatus, interval time.Duration, now time.Time) bool {
+	return interval > 0 && status.RotatedAt != nil && !now.Before(status.RotatedAt.Add(interval))
+}
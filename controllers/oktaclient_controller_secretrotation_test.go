@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"context"
+	"github.com/jaconi-io/okta-operator/api/v1alpha1"
+	"github.com/jaconi-io/okta-operator/okta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+	"time"
+)
+
+var testRotationClient = v1alpha1.OktaClient{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:      "test-client",
+		Namespace: "default",
+	},
+	Spec: v1alpha1.OktaClientSpec{
+		Name: "test-client",
+		SecretRotation: &v1alpha1.SecretRotation{
+			Interval:    metav1.Duration{Duration: time.Hour},
+			GracePeriod: &metav1.Duration{Duration: time.Minute},
+		},
+	},
+}
+
+func TestRotateClientSecretNotDue(t *testing.T) {
+	t.Parallel()
+	oktaAPI := newOktaMock()
+	now := time.Now()
+	oktaAPI.clientSecrets = []okta.ClientSecret{{ID: "initial", Status: "ACTIVE", Created: now.Add(-time.Minute)}}
+	oktaClient := testRotationClient.DeepCopy()
+
+	secret, rotated, err := rotateClientSecret(oktaClient, context.Background(), &testApp, now, oktaAPI)
+	if err != nil {
+		t.Fatalf("error rotating client secret: %v", err)
+	}
+	if secret != nil || rotated != nil {
+		t.Errorf("got rotated client secret %v, wanted none", secret)
+	}
+	if oktaClient.Status.SecretRotation.SecretID != "initial" {
+		t.Errorf("got client secret %q, wanted %q", oktaClient.Status.SecretRotation.SecretID, "initial")
+	}
+	if next := nextSecretRotation(oktaClient, now); next != 59*time.Minute {
+		t.Errorf("got next rotation in %s, wanted %s", next, 59*time.Minute)
+	}
+}
+
+func TestRotateClientSecretDue(t *testing.T) {
+	t.Parallel()
+	oktaAPI := newOktaMock()
+	now := time.Now()
+	oktaAPI.clientSecrets = []okta.ClientSecret{{ID: "initial", Status: "ACTIVE"}, {ID: "orphan", Status: "ACTIVE"}}
+	oktaClient := testRotationClient.DeepCopy()
+	oktaClient.Status.SecretRotation = &v1alpha1.SecretRotationStatus{
+		SecretID:  "initial",
+		RotatedAt: &metav1.Time{Time: now.Add(-2 * time.Hour)},
+	}
+
+	secret, rotated, err := rotateClientSecret(oktaClient, context.Background(), &testApp, now, oktaAPI)
+	if err != nil {
+		t.Fatalf("error rotating client secret: %v", err)
+	}
+	if secret == nil || rotated == nil || rotated.SecretID != secret.ID {
+		t.Fatalf("got rotated client secret %v, wanted a new client secret", secret)
+	}
+	if rotated.PreviousSecretID != "initial" || !rotated.PreviousSecretExpiresAt.Time.Equal(now.Add(time.Minute)) {
+		t.Errorf("got previous client secret %q expiring at %v, wanted %q", rotated.PreviousSecretID, rotated.PreviousSecretExpiresAt, "initial")
+	}
+	if oktaAPI.clientSecretsDeleted != 1 || len(oktaAPI.clientSecrets) != 2 {
+		t.Errorf("got %d deletions and %d client secrets, wanted %d and %d", oktaAPI.clientSecretsDeleted, len(oktaAPI.clientSecrets), 1, 2)
+	}
+}
+
+func TestRotateClientSecretGracePeriodPassed(t *testing.T) {
+	t.Parallel()
+	oktaAPI := newOktaMock()
+	now := time.Now()
+	oktaAPI.clientSecrets = []okta.ClientSecret{{ID: "previous", Status: "ACTIVE"}, {ID: "current", Status: "ACTIVE"}}
+	oktaClient := testRotationClient.DeepCopy()
+	oktaClient.Status.SecretRotation = &v1alpha1.SecretRotationStatus{
+		SecretID:                "current",
+		RotatedAt:               &metav1.Time{Time: now.Add(-2 * time.Minute)},
+		PreviousSecretID:        "previous",
+		PreviousSecretExpiresAt: &metav1.Time{Time: now.Add(-time.Minute)},
+	}
+
+	secret, _, err := rotateClientSecret(oktaClient, context.Background(), &testApp, now, oktaAPI)
+	if err != nil {
+		t.Fatalf("error rotating client secret: %v", err)
+	}
+	if secret != nil {
+		t.Errorf("got rotated client secret %v, wanted none", secret)
+	}
+	if len(oktaAPI.clientSecrets) != 1 || oktaClient.Status.SecretRotation.PreviousSecretID != "" {
+		t.Errorf("got %d client secrets and previous client secret %q, wanted only the current one", len(oktaAPI.clientSecrets), oktaClient.Status.SecretRotation.PreviousSecretID)
+	}
+}
+
+func TestUpdateApplicationRotatesClientSecret(t *testing.T) {
+	t.Parallel()
+	oktaAPI := newOktaMock()
+	oktaAPI.addApplication(applicationSettings(&testRotationClient))
+	oktaAPI.clientSecrets = []okta.ClientSecret{{ID: "initial", Status: "ACTIVE"}}
+	oktaClient := testRotationClient.DeepCopy()
+	oktaClient.Status.SecretRotation = &v1alpha1.SecretRotationStatus{
+		SecretID:  "initial",
+		RotatedAt: &metav1.Time{Time: time.Now().Add(-2 * time.Hour)},
+	}
+
+	kubernetesClient := newTestClient(newTestSecret(map[string][]byte{secretKeyClientSecret: []byte("initial")}))
+	err := updateApplication(oktaClient, context.Background(), testRequest, kubernetesClient, oktaAPI)
+	if err != nil {
+		t.Errorf("error updating application: %v", err)
+	}
+	if oktaAPI.clientSecretsCreated != 1 {
+		t.Errorf("got %d method calls, wanted %d", oktaAPI.clientSecretsCreated, 1)
+	}
+	if oktaClient.Status.SecretRotation.PreviousSecretID != "initial" {
+		t.Errorf("got previous client secret %q, wanted %q", oktaClient.Status.SecretRotation.PreviousSecretID, "initial")
+	}
+}
+
+func TestRotateClientSecretOnDemand(t *testing.T) {
+	t.Parallel()
+	oktaAPI := newOktaMock()
+	now := time.Now()
+	oktaAPI.clientSecrets = []okta.ClientSecret{{ID: "previous", Status: "ACTIVE"}, {ID: "current", Status: "ACTIVE"}}
+	oktaClient := testRotationClient.DeepCopy()
+	oktaClient.Status.SecretRotation = &v1alpha1.SecretRotationStatus{
+		SecretID:                "current",
+		RotatedAt:               &metav1.Time{Time: now.Add(-2 * time.Minute)},
+		PreviousSecretID:        "previous",
+		PreviousSecretExpiresAt: &metav1.Time{Time: now.Add(time.Minute)},
+	}
+
+	secret, rotated, err := rotateClientSecretOnDemand(oktaClient, context.Background(), &testApp, now, oktaAPI)
+	if err != nil {
+		t.Fatalf("error rotating client secret: %v", err)
+	}
+	if secret == nil || rotated == nil || rotated.SecretID != secret.ID {
+		t.Fatalf("got rotated client secret %v, wanted a new client secret", secret)
+	}
+	if rotated.PreviousSecretID != "current" || !rotated.PreviousSecretExpiresAt.Time.Equal(now) {
+		t.Errorf("got previous client secret %q expiring at %v, wanted %q expiring right away", rotated.PreviousSecretID, rotated.PreviousSecretExpiresAt, "current")
+	}
+	if oktaAPI.clientSecretsDeleted != 1 || len(oktaAPI.clientSecrets) != 2 {
+		t.Errorf("got %d deletions and %d client secrets, wanted %d and %d", oktaAPI.clientSecretsDeleted, len(oktaAPI.clientSecrets), 1, 2)
+	}
+}
+
+func TestUpdateApplicationRotatesClientSecretOnDemand(t *testing.T) {
+	t.Parallel()
+	oktaAPI := newOktaMock()
+	oktaClient := testAppClient.DeepCopy()
+	oktaAPI.addApplication(applicationSettings(oktaClient))
+	oktaAPI.clientSecrets = []okta.ClientSecret{{ID: "initial", Status: "ACTIVE"}}
+	oktaClient.Annotations = map[string]string{annotationRotateSecret: "2024-01-01T00:00:00Z"}
+
+	kubernetesClient := newTestClient(newTestSecret(nil))
+	err := updateApplication(oktaClient, context.Background(), testRequest, kubernetesClient, oktaAPI)
+	if err != nil {
+		t.Errorf("error updating application: %v", err)
+	}
+	if oktaAPI.clientSecretsCreated != 1 {
+		t.Errorf("got %d method calls, wanted %d", oktaAPI.clientSecretsCreated, 1)
+	}
+	if oktaClient.Status.LastSecretRotationRequest != "2024-01-01T00:00:00Z" {
+		t.Errorf("got last rotation request %q, wanted %q", oktaClient.Status.LastSecretRotationRequest, "2024-01-01T00:00:00Z")
+	}
+
+	// The same annotation value must not trigger another rotation.
+	err = updateApplication(oktaClient, context.Background(), testRequest, kubernetesClient, oktaAPI)
+	if err != nil {
+		t.Errorf("error updating application: %v", err)
+	}
+	if oktaAPI.clientSecretsCreated != 1 {
+		t.Errorf("got %d method calls, wanted %d", oktaAPI.clientSecretsCreated, 1)
+	}
+}
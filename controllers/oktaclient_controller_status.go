@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	oktav1alpha1 "github.com/jaconi-io/okta-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// updateStatus records the outcome of a reconciliation in the status of the OktaClient. The given reconciliation error
+// is returned as is, so callers can simply return the result of this method.
+func (r *OktaClientReconciler) updateStatus(oktaClient *oktav1alpha1.OktaClient, ctx context.Context, reason string, reconcileErr error) error {
+	setStatusConditions(oktaClient, reason, reconcileErr)
+	reconcileOutcomes.WithLabelValues(reason).Inc()
+
+	err := r.Status().Update(ctx, oktaClient)
+	if err != nil {
+		if reconcileErr != nil {
+			return reconcileErr
+		}
+		return fmt.Errorf("failed to update status of oktaClient %q: %w", oktaClient.Name, err)
+	}
+
+	return reconcileErr
+}
+
+// setStatusConditions sets the Ready and Error conditions as well as the observed generation of the OktaClient.
+func setStatusConditions(oktaClient *oktav1alpha1.OktaClient, reason string, reconcileErr error) {
+	ready := metav1.Condition{
+		Type:               ConditionTypeSynced,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: oktaClient.Generation,
+		Reason:             reason,
+		Message:            "Okta application and trusted origins are in sync",
+	}
+	failed := metav1.Condition{
+		Type:               ConditionTypeError,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: oktaClient.Generation,
+		Reason:             reason,
+	}
+
+	if reconcileErr != nil {
+		ready.Status = metav1.ConditionFalse
+		ready.Message = reconcileErr.Error()
+		failed.Status = metav1.ConditionTrue
+		failed.Message = reconcileErr.Error()
+	}
+
+	meta.SetStatusCondition(&oktaClient.Status.Conditions, ready)
+	meta.SetStatusCondition(&oktaClient.Status.Conditions, failed)
+	oktaClient.Status.ObservedGeneration = oktaClient.Generation
+}
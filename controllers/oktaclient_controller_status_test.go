@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jaconi-io/okta-operator/okta"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"testing"
+	"time"
+)
+
+func TestSetStatusConditionsSynced(t *testing.T) {
+	oktaClient := testAppClient.DeepCopy()
+	oktaClient.Generation = 2
+
+	setStatusConditions(oktaClient, ReasonSynced, nil)
+
+	if !meta.IsStatusConditionTrue(oktaClient.Status.Conditions, ConditionTypeSynced) {
+		t.Errorf("got condition %q not true, wanted true", ConditionTypeSynced)
+	}
+	if !meta.IsStatusConditionFalse(oktaClient.Status.Conditions, ConditionTypeError) {
+		t.Errorf("got condition %q not false, wanted false", ConditionTypeError)
+	}
+	if oktaClient.Status.ObservedGeneration != 2 {
+		t.Errorf("got observed generation %d, wanted %d", oktaClient.Status.ObservedGeneration, 2)
+	}
+}
+
+func TestSetStatusConditionsError(t *testing.T) {
+	oktaClient := testAppClient.DeepCopy()
+	setStatusConditions(oktaClient, ReasonSynced, nil)
+
+	setStatusConditions(oktaClient, ReasonApplicationFailed, errors.New("boom"))
+
+	if len(oktaClient.Status.Conditions) != 2 {
+		t.Errorf("got %d conditions, wanted %d", len(oktaClient.Status.Conditions), 2)
+	}
+	ready := meta.FindStatusCondition(oktaClient.Status.Conditions, ConditionTypeSynced)
+	if ready.Status != "False" || ready.Reason != ReasonApplicationFailed || ready.Message != "boom" {
+		t.Errorf("got condition %+v, wanted failed condition", ready)
+	}
+	if !meta.IsStatusConditionTrue(oktaClient.Status.Conditions, ConditionTypeError) {
+		t.Errorf("got condition %q not true, wanted true", ConditionTypeError)
+	}
+}
+
+func TestFailedRateLimited(t *testing.T) {
+	t.Parallel()
+	oktaClient := testAppClient.DeepCopy()
+	r := &OktaClientReconciler{Client: newTestClient(oktaClient)}
+
+	reset := time.Now().Add(time.Minute)
+	err := fmt.Errorf("failed to create application: %w", &okta.RateLimitError{Endpoint: "/api/v1/apps", Reset: reset})
+	result, err := r.failed(oktaClient, context.Background(), ReasonApplicationFailed, err)
+	if err != nil || result.RequeueAfter <= 0 || result.RequeueAfter > time.Minute {
+		t.Errorf("got result %+v and error %v, wanted requeue once the rate limit resets", result, err)
+	}
+	ready := meta.FindStatusCondition(oktaClient.Status.Conditions, ConditionTypeSynced)
+	if ready == nil || ready.Reason != ReasonRateLimited {
+		t.Errorf("got condition %+v, wanted reason %q", ready, ReasonRateLimited)
+	}
+
+	result, err = r.failed(oktaClient, context.Background(), ReasonApplicationFailed, errors.New("boom"))
+	if err == nil || !result.IsZero() {
+		t.Errorf("got result %+v and error %v, wanted error", result, err)
+	}
+}
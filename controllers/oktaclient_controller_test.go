@@ -6,6 +6,8 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime/pkg/client"
@@ -25,9 +27,7 @@ var _ = Describe("OktaClient controller", func() {
 	ns := &core.Namespace{}
 
 	BeforeEach(func() {
-		resetToCluster()
-		getAppByLabel = getAppByLabelMock
-		createApp = appCreatorMock
+		testOkta.Reset()
 
 		*ns = core.Namespace{
 			ObjectMeta: metav1.ObjectMeta{Name: "testns-" + randStringRunes(5)},
@@ -72,7 +72,7 @@ var _ = Describe("OktaClient controller", func() {
 
 			// App created
 			Eventually(func() int {
-				return len(testOktaClients)
+				return len(testOkta.ApplicationLabels())
 			}, timeout, interval).Should(Equal(1))
 
 			// We'll need to retry getting this newly created Secret, given that creation may not immediately happen.
@@ -84,6 +84,22 @@ var _ = Describe("OktaClient controller", func() {
 				return true
 			}, timeout, interval).Should(BeTrue())
 
+			// Status reports the application as ready
+			oktaClientLookupKey := types.NamespacedName{Name: OktaClientName, Namespace: ns.Name}
+			Eventually(func() bool {
+				err := k8sClient.Get(ctx, oktaClientLookupKey, oktaClient)
+				if err != nil {
+					return false
+				}
+				return meta.IsStatusConditionTrue(oktaClient.Status.Conditions, ConditionTypeSynced)
+			}, timeout, interval).Should(BeTrue())
+			Expect(oktaClient.Status.ApplicationID).Should(Equal(testApp.ID))
+			Expect(oktaClient.Status.SecretName).Should(Equal(OktaClientName))
+			Expect(oktaClient.Status.TrustedOrigins).Should(ConsistOf("a", "b"))
+
+			// Secret is owned by the OktaClient
+			Expect(metav1.IsControlledBy(createdSecret, oktaClient)).Should(BeTrue())
+
 		})
 	})
 
@@ -115,12 +131,12 @@ var _ = Describe("OktaClient controller", func() {
 
 			// App created
 			Eventually(func() int {
-				return len(testOktaClients)
+				return len(testOkta.ApplicationLabels())
 			}, timeout, interval).Should(Equal(1))
 
 			// Trusted Origins created
 			Eventually(func() int {
-				return len(testTrustedOrigins)
+				return len(testOkta.TrustedOrigins())
 			}, timeout, interval).Should(Equal(2))
 
 			// We'll need to retry getting this newly created Secret, given that creation may not immediately happen.
@@ -136,12 +152,12 @@ var _ = Describe("OktaClient controller", func() {
 
 			// App deleted
 			Eventually(func() int {
-				return len(testOktaClients)
+				return len(testOkta.ApplicationLabels())
 			}, timeout, interval).Should(Equal(0))
 
 			// Trusted Origins deleted
 			Eventually(func() int {
-				return len(testTrustedOrigins)
+				return len(testOkta.TrustedOrigins())
 			}, timeout, interval).Should(Equal(0))
 
 			// Created secret stays there
@@ -153,6 +169,105 @@ var _ = Describe("OktaClient controller", func() {
 				return true
 			}, timeout, interval).Should(BeTrue())
 
+			// Retained secret is no longer owned by the OktaClient
+			Expect(metav1.IsControlledBy(createdSecret, oktaClient)).Should(BeFalse())
+
+		})
+	})
+
+	Context("When deleting an OktaClient with secret deletion policy Delete", func() {
+		It("Should Delete the Application and the Secret", func() {
+			ctx := context.Background()
+			oktaClient := &v1alpha1.OktaClient{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "okta.jaconi.io/v1alpha1",
+					Kind:       "OktaClient",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      OktaClientName,
+					Namespace: ns.Name,
+				},
+				Spec: v1alpha1.OktaClientSpec{
+					Name:                 OktaClientName,
+					SecretDeletionPolicy: v1alpha1.SecretDeletionPolicyDelete,
+				},
+			}
+
+			Expect(k8sClient.Create(ctx, oktaClient)).Should(Succeed())
+
+			secretLookupKey := types.NamespacedName{Name: OktaClientName, Namespace: ns.Name}
+			createdSecret := &core.Secret{}
+
+			// We'll need to retry getting this newly created Secret, given that creation may not immediately happen.
+			Eventually(func() bool {
+				err := k8sClient.Get(ctx, secretLookupKey, createdSecret)
+				if err != nil {
+					return false
+				}
+				return true
+			}, timeout, interval).Should(BeTrue())
+
+			Expect(k8sClient.Delete(ctx, oktaClient)).Should(Succeed())
+
+			// App deleted
+			Eventually(func() int {
+				return len(testOkta.ApplicationLabels())
+			}, timeout, interval).Should(Equal(0))
+
+			// Secret deleted
+			Eventually(func() bool {
+				err := k8sClient.Get(ctx, secretLookupKey, createdSecret)
+				return errors.IsNotFound(err)
+			}, timeout, interval).Should(BeTrue())
+
+		})
+	})
+
+	Context("When deleting one of two OktaClients sharing a trusted origin", func() {
+		It("Should keep the shared trusted origin", func() {
+			ctx := context.Background()
+			newOktaClient := func(name string, origins ...string) *v1alpha1.OktaClient {
+				return &v1alpha1.OktaClient{
+					TypeMeta: metav1.TypeMeta{
+						APIVersion: "okta.jaconi.io/v1alpha1",
+						Kind:       "OktaClient",
+					},
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      name,
+						Namespace: ns.Name,
+					},
+					Spec: v1alpha1.OktaClientSpec{
+						Name:           name,
+						TrustedOrigins: origins,
+					},
+				}
+			}
+			oktaClient := newOktaClient(OktaClientName, "a", "shared")
+			otherOktaClient := newOktaClient(OktaClientName+"-other", "shared")
+
+			Expect(k8sClient.Create(ctx, oktaClient)).Should(Succeed())
+			Expect(k8sClient.Create(ctx, otherOktaClient)).Should(Succeed())
+
+			// Apps and trusted origins created
+			Eventually(func() int {
+				return len(testOkta.ApplicationLabels())
+			}, timeout, interval).Should(Equal(2))
+			Eventually(func() int {
+				return len(testOkta.TrustedOrigins())
+			}, timeout, interval).Should(Equal(2))
+
+			Expect(k8sClient.Delete(ctx, oktaClient)).Should(Succeed())
+
+			// Only the trusted origin not referenced by the other OktaClient is deleted
+			Eventually(func() []string {
+				return testOkta.TrustedOrigins()
+			}, timeout, interval).Should(ConsistOf("shared"))
+
+			Expect(k8sClient.Delete(ctx, otherOktaClient)).Should(Succeed())
+
+			Eventually(func() int {
+				return len(testOkta.TrustedOrigins())
+			}, timeout, interval).Should(Equal(0))
 		})
 	})
 })
@@ -5,21 +5,22 @@ import (
 	"fmt"
 	oktav1alpha1 "github.com/jaconi-io/okta-operator/api/v1alpha1"
 	"github.com/jaconi-io/okta-operator/okta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"slices"
 )
 
-var (
-	isTrustedOrigin     = okta.IsTrustedOrigin
-	createTrustedOrigin = okta.CreateTrustedOrigin
-	deleteTrustedOrigin = okta.DeleteTrustedOrigin
-)
+// trustedOriginsIndex indexes OktaClients by the trusted origins in their spec and status. Trusted origins are managed
+// per Okta organization, so the keys are qualified by the host of the organization recorded in the status, see
+// trustedOriginKey.
+const trustedOriginsIndex = "trustedOrigins"
 
-func updateTrustedOrigins(oktaClient *oktav1alpha1.OktaClient, ctx context.Context) error {
+func updateTrustedOrigins(oktaClient *oktav1alpha1.OktaClient, ctx context.Context, kubernetesClient client.Client, oktaAPI okta.API) error {
 	// Create trusted origins
 	log := ctrllog.FromContext(ctx)
 	origins := oktaClient.Spec.TrustedOrigins
 	for _, origin := range origins {
-		isTrustedOrigin, err := isTrustedOrigin(origin)
+		isTrustedOrigin, err := oktaAPI.IsTrustedOrigin(ctx, origin)
 		log.Info("Queried trusted origin", "origin", origin, "exists", isTrustedOrigin)
 		if err != nil {
 			return fmt.Errorf("failed to determine if %q is a trusted origin: %w", origin, err)
@@ -29,28 +30,69 @@ func updateTrustedOrigins(oktaClient *oktav1alpha1.OktaClient, ctx context.Conte
 			continue
 		}
 		log.Info("Creating trusted origin", "origin", origin)
-		err = createTrustedOrigin(origin)
+		err = oktaAPI.CreateTrustedOrigin(ctx, origin)
 
 		if err != nil {
 			return fmt.Errorf("failed to create trusted origin %q: %w", origin, err)
 		}
 	}
 
+	// Prune trusted origins, that have been removed from the spec since the last reconciliation.
+	var removed []string
+	for _, origin := range oktaClient.Status.TrustedOrigins {
+		if !slices.Contains(origins, origin) {
+			removed = append(removed, origin)
+		}
+	}
+	err := deleteOrigins(oktaClient, removed, oktav1alpha1.DeletionPolicyDelete, ctx, kubernetesClient, oktaAPI)
+	if err != nil {
+		return err
+	}
+
+	oktaClient.Status.TrustedOrigins = append([]string(nil), origins...)
+
 	return nil
 }
 
-func deleteTrustedOrigins(oktaClient *oktav1alpha1.OktaClient, ctx context.Context) error {
+// deleteTrustedOrigins deletes or deactivates the trusted origins of an OktaClient, depending on the deletion policy.
+// Orphaned trusted origins are left untouched.
+func deleteTrustedOrigins(oktaClient *oktav1alpha1.OktaClient, ctx context.Context, kubernetesClient client.Client, policy oktav1alpha1.DeletionPolicy, oktaAPI okta.API) error {
+	if policy == oktav1alpha1.DeletionPolicyOrphan {
+		ctrllog.FromContext(ctx).Info("Orphaning trusted origins", "origins", referencedTrustedOrigins(oktaClient))
+		return nil
+	}
+	return deleteOrigins(oktaClient, referencedTrustedOrigins(oktaClient), policy, ctx, kubernetesClient, oktaAPI)
+}
+
+// deleteOrigins deletes or deactivates the given trusted origins in Okta, if they exist and no other OktaClient
+// references them.
+func deleteOrigins(oktaClient *oktav1alpha1.OktaClient, origins []string, policy oktav1alpha1.DeletionPolicy, ctx context.Context, kubernetesClient client.Client, oktaAPI okta.API) error {
 	log := ctrllog.FromContext(ctx)
-	origins := oktaClient.Spec.TrustedOrigins
 	for _, origin := range origins {
-		isTrustedOrigin, err := isTrustedOrigin(origin)
+		referenced, err := isTrustedOriginReferenced(kubernetesClient, ctx, oktaClient, oktaAPI.OrgURL(), origin)
+		if err != nil {
+			return fmt.Errorf("failed to determine if trusted origin %q is still in use: %w", origin, err)
+		}
+		if referenced {
+			log.Info("Keeping trusted origin referenced by another OktaClient", "origin", origin)
+			continue
+		}
+
+		isTrustedOrigin, err := oktaAPI.IsTrustedOrigin(ctx, origin)
 		log.Info("Queried trusted origin", "origin", origin, "exists", isTrustedOrigin)
 		if err != nil {
 			return fmt.Errorf("failed to determine if %q is a trusted origin: %w", origin, err)
 		}
-		if isTrustedOrigin {
+		if isTrustedOrigin && policy == oktav1alpha1.DeletionPolicyDeactivate {
+			log.Info("Deactivating trusted origin", "origin", origin)
+			err = oktaAPI.DeactivateTrustedOrigin(ctx, origin)
+
+			if err != nil {
+				return fmt.Errorf("failed to deactivate trusted origin %q: %w", origin, err)
+			}
+		} else if isTrustedOrigin {
 			log.Info("Deleting trusted origin", "origin", origin)
-			err = deleteTrustedOrigin(origin)
+			err = oktaAPI.DeleteTrustedOrigin(ctx, origin)
 
 			if err != nil {
 				return fmt.Errorf("failed to delete trusted origin %q: %w", origin, err)
@@ -59,3 +101,53 @@ func deleteTrustedOrigins(oktaClient *oktav1alpha1.OktaClient, ctx context.Conte
 	}
 	return nil
 }
+
+// isTrustedOriginReferenced returns true, if an OktaClient other than the given one references the trusted origin in
+// the Okta organization with the given URL, no matter which OktaOrg or ClusterOktaOrg it references. OktaClients,
+// whose organization is not known yet, might reference it as well. OktaClients that are being deleted are ignored.
+func isTrustedOriginReferenced(k8sClient client.Client, ctx context.Context, oktaClient *oktav1alpha1.OktaClient, orgURL string, origin string) (bool, error) {
+	for _, orgHost := range []string{urlHost(orgURL), ""} {
+		oktaClients := &oktav1alpha1.OktaClientList{}
+		err := k8sClient.List(ctx, oktaClients, client.MatchingFields{trustedOriginsIndex: trustedOriginKey(orgHost, origin)})
+		if err != nil {
+			return false, err
+		}
+
+		for _, other := range oktaClients.Items {
+			if other.UID != oktaClient.UID && other.GetDeletionTimestamp() == nil {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// indexTrustedOrigins returns the keys of the trusted origins referenced by an OktaClient for the trustedOriginsIndex.
+func indexTrustedOrigins(obj client.Object) []string {
+	oktaClient := obj.(*oktav1alpha1.OktaClient)
+
+	var keys []string
+	for _, origin := range referencedTrustedOrigins(oktaClient) {
+		keys = append(keys, trustedOriginKey(urlHost(oktaClient.Status.OrgURL), origin))
+	}
+	return keys
+}
+
+// trustedOriginKey returns the key of the trustedOriginsIndex for the trusted origin in the Okta organization with the
+// given host. The host is empty, if the organization of the OktaClient is not known yet.
+func trustedOriginKey(orgHost string, origin string) string {
+	return orgHost + "|" + origin
+}
+
+// referencedTrustedOrigins returns the trusted origins in the spec as well as those recorded in the status, that might
+// not have been pruned yet.
+func referencedTrustedOrigins(oktaClient *oktav1alpha1.OktaClient) []string {
+	origins := append([]string(nil), oktaClient.Spec.TrustedOrigins...)
+	for _, origin := range oktaClient.Status.TrustedOrigins {
+		if !slices.Contains(origins, origin) {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
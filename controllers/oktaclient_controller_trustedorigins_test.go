@@ -1,54 +1,255 @@
 package controllers
 
 import (
+	"context"
+	"github.com/jaconi-io/okta-operator/api/v1alpha1"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"testing"
+	"time"
 )
 
 func TestUpdateTrustedOriginsAlreadyTrusted(t *testing.T) {
-	resetToLocal()
-	_ = addTestTrustedOrigin("a")
-	_ = addTestTrustedOrigin("b")
+	t.Parallel()
+	oktaAPI := newOktaMock()
+	oktaAPI.addTrustedOrigin("a")
+	oktaAPI.addTrustedOrigin("b")
 
-	err := updateTrustedOrigins(&testToClient, nil)
+	err := updateTrustedOrigins(&testToClient, context.Background(), newTestClient(), oktaAPI)
 	if err != nil {
 		t.Errorf("error calling method")
 	}
-	if len(testTrustedOrigins) != 2 {
-		t.Errorf("got %d origins, wanted %d", len(testTrustedOrigins), 2)
+	if len(oktaAPI.trustedOrigins) != 2 {
+		t.Errorf("got %d origins, wanted %d", len(oktaAPI.trustedOrigins), 2)
 	}
-	if trustedOriginsCreated != 0 {
-		t.Errorf("got %d method calls, wanted %d", trustedOriginsCreated, 0)
+	if oktaAPI.trustedOriginsCreated != 0 {
+		t.Errorf("got %d method calls, wanted %d", oktaAPI.trustedOriginsCreated, 0)
 	}
 }
 
 func TestUpdateTrustedOriginsNotAlreadyTrusted(t *testing.T) {
-	resetToLocal()
+	t.Parallel()
+	oktaAPI := newOktaMock()
 
-	err := updateTrustedOrigins(&testToClient, nil)
+	err := updateTrustedOrigins(&testToClient, context.Background(), newTestClient(), oktaAPI)
 	if err != nil {
 		t.Errorf("error calling method")
 	}
-	if len(testTrustedOrigins) != 2 {
-		t.Errorf("got %d method calls, wanted %d", len(testTrustedOrigins), 2)
+	if len(oktaAPI.trustedOrigins) != 2 {
+		t.Errorf("got %d method calls, wanted %d", len(oktaAPI.trustedOrigins), 2)
 	}
-	if trustedOriginsCreated != 2 {
-		t.Errorf("got %d method calls, wanted %d", trustedOriginsCreated, 2)
+	if oktaAPI.trustedOriginsCreated != 2 {
+		t.Errorf("got %d method calls, wanted %d", oktaAPI.trustedOriginsCreated, 2)
 	}
 }
 
 func TestDeleteTrustedOrigins(t *testing.T) {
-	resetToLocal()
-	_ = addTestTrustedOrigin("a")
-	_ = addTestTrustedOrigin("b")
+	t.Parallel()
+	oktaAPI := newOktaMock()
+	oktaAPI.addTrustedOrigin("a")
+	oktaAPI.addTrustedOrigin("b")
 
-	err := deleteTrustedOrigins(&testToClient, nil)
+	err := deleteTrustedOrigins(&testToClient, context.Background(), newTestClient(), v1alpha1.DeletionPolicyDelete, oktaAPI)
 	if err != nil {
 		t.Errorf("error calling method")
 	}
-	if len(testTrustedOrigins) != 0 {
-		t.Errorf("got %d method calls, wanted %d", len(testTrustedOrigins), 0)
+	if len(oktaAPI.trustedOrigins) != 0 {
+		t.Errorf("got %d method calls, wanted %d", len(oktaAPI.trustedOrigins), 0)
 	}
-	if trustedOriginsDeleted != 2 {
-		t.Errorf("got %d method calls, wanted %d", trustedOriginsDeleted, 2)
+	if oktaAPI.trustedOriginsDeleted != 2 {
+		t.Errorf("got %d method calls, wanted %d", oktaAPI.trustedOriginsDeleted, 2)
+	}
+}
+
+func TestUpdateTrustedOriginsRemovedFromSpec(t *testing.T) {
+	t.Parallel()
+	oktaAPI := newOktaMock()
+	oktaAPI.addTrustedOrigin("a")
+	oktaAPI.addTrustedOrigin("b")
+	oktaAPI.addTrustedOrigin("c")
+
+	oktaClient := testToClient.DeepCopy()
+	oktaClient.Status.TrustedOrigins = []string{"a", "b", "c"}
+
+	err := updateTrustedOrigins(oktaClient, context.Background(), newTestClient(), oktaAPI)
+	if err != nil {
+		t.Errorf("error calling method")
+	}
+	if len(oktaAPI.trustedOrigins) != 2 {
+		t.Errorf("got %d origins, wanted %d", len(oktaAPI.trustedOrigins), 2)
+	}
+	if oktaAPI.trustedOriginsDeleted != 1 {
+		t.Errorf("got %d method calls, wanted %d", oktaAPI.trustedOriginsDeleted, 1)
+	}
+	if len(oktaClient.Status.TrustedOrigins) != 2 {
+		t.Errorf("got %d origins in status, wanted %d", len(oktaClient.Status.TrustedOrigins), 2)
+	}
+}
+
+func TestDeleteTrustedOriginsNotYetPruned(t *testing.T) {
+	t.Parallel()
+	oktaAPI := newOktaMock()
+	oktaAPI.addTrustedOrigin("a")
+	oktaAPI.addTrustedOrigin("b")
+	oktaAPI.addTrustedOrigin("c")
+
+	oktaClient := testToClient.DeepCopy()
+	oktaClient.Status.TrustedOrigins = []string{"a", "c"}
+
+	err := deleteTrustedOrigins(oktaClient, context.Background(), newTestClient(), v1alpha1.DeletionPolicyDelete, oktaAPI)
+	if err != nil {
+		t.Errorf("error calling method")
+	}
+	if len(oktaAPI.trustedOrigins) != 0 {
+		t.Errorf("got %d origins, wanted %d", len(oktaAPI.trustedOrigins), 0)
+	}
+	if oktaAPI.trustedOriginsDeleted != 3 {
+		t.Errorf("got %d method calls, wanted %d", oktaAPI.trustedOriginsDeleted, 3)
+	}
+}
+
+func TestDeleteTrustedOriginsReferencedElsewhere(t *testing.T) {
+	t.Parallel()
+	oktaAPI := newOktaMock()
+	oktaAPI.addTrustedOrigin("a")
+	oktaAPI.addTrustedOrigin("b")
+	otherOktaClient := &v1alpha1.OktaClient{
+		ObjectMeta: metav1.ObjectMeta{Name: "other-client", Namespace: "default", UID: "other"},
+		Spec:       v1alpha1.OktaClientSpec{TrustedOrigins: []string{"b"}},
+	}
+
+	err := deleteTrustedOrigins(testToClient.DeepCopy(), context.Background(), newTestClient(otherOktaClient), v1alpha1.DeletionPolicyDelete, oktaAPI)
+	if err != nil {
+		t.Errorf("error calling method")
+	}
+	if len(oktaAPI.trustedOrigins) != 1 {
+		t.Errorf("got %d origins, wanted %d", len(oktaAPI.trustedOrigins), 1)
+	}
+	if oktaAPI.trustedOriginsDeleted != 1 {
+		t.Errorf("got %d method calls, wanted %d", oktaAPI.trustedOriginsDeleted, 1)
+	}
+}
+
+func TestDeleteTrustedOriginsReferencedInOtherOrg(t *testing.T) {
+	t.Parallel()
+	oktaAPI := newOktaMock()
+	oktaAPI.addTrustedOrigin("a")
+	oktaAPI.addTrustedOrigin("b")
+	otherOktaClient := &v1alpha1.OktaClient{
+		ObjectMeta: metav1.ObjectMeta{Name: "other-client", Namespace: "default", UID: "other"},
+		Spec: v1alpha1.OktaClientSpec{
+			TrustedOrigins: []string{"b"},
+			OrgRef:         &v1alpha1.OrgReference{Kind: v1alpha1.ClusterOktaOrgKind, Name: "other"},
+		},
+		Status: v1alpha1.OktaClientStatus{OrgURL: "https://other.okta.com"},
+	}
+
+	// The trusted origin of the other OktaClient is managed in another Okta organization.
+	err := deleteTrustedOrigins(testToClient.DeepCopy(), context.Background(), newTestClient(otherOktaClient), v1alpha1.DeletionPolicyDelete, oktaAPI)
+	if err != nil {
+		t.Errorf("error deleting trusted origins: %v", err)
+	}
+	if len(oktaAPI.trustedOrigins) != 0 || oktaAPI.trustedOriginsDeleted != 2 {
+		t.Errorf("got %d origins and %d deletions, wanted %d and %d", len(oktaAPI.trustedOrigins), oktaAPI.trustedOriginsDeleted, 0, 2)
+	}
+}
+
+func TestDeleteTrustedOriginsReferencedInSameOrg(t *testing.T) {
+	t.Parallel()
+	const orgURL = "https://example.okta.com"
+	var objects []client.Object
+	var requests []ctrl.Request
+	for _, namespace := range []string{"team-a", "team-b"} {
+		objects = append(objects,
+			&v1alpha1.OktaOrg{
+				ObjectMeta: metav1.ObjectMeta{Name: "okta", Namespace: namespace},
+				Spec: v1alpha1.OktaOrgSpec{
+					OrgURL:               orgURL,
+					CredentialsSecretRef: &v1alpha1.CredentialsSecretReference{Name: "okta"},
+				},
+			},
+			&core.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "okta", Namespace: namespace},
+				Data:       map[string][]byte{"OKTA_CLIENT_TOKEN": []byte("token")},
+			},
+			&v1alpha1.OktaClient{
+				ObjectMeta: metav1.ObjectMeta{Name: "client", Namespace: namespace, UID: types.UID(namespace)},
+				Spec: v1alpha1.OktaClientSpec{
+					Name:           "client-" + namespace,
+					OrgRef:         &v1alpha1.OrgReference{Kind: v1alpha1.OktaOrgKind, Name: "okta"},
+					TrustedOrigins: []string{"https://shared.example.com"},
+				},
+			},
+		)
+		requests = append(requests, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: namespace, Name: "client"}})
+	}
+
+	orgs := newOrgsMock()
+	r := &OktaClientReconciler{
+		Client:         newTestClient(objects...),
+		Recorder:       record.NewFakeRecorder(10),
+		Orgs:           orgs,
+		ResyncInterval: time.Minute,
+	}
+	ctx := context.Background()
+	for _, req := range requests {
+		_, err := r.Reconcile(ctx, req)
+		if err != nil {
+			t.Fatalf("error reconciling oktaClient %q: %v", req.NamespacedName, err)
+		}
+	}
+
+	// Both OktaOrgs point at the same Okta organization, so the trusted origin is still used by the other OktaClient.
+	oktaClient := &v1alpha1.OktaClient{}
+	err := r.Get(ctx, requests[0].NamespacedName, oktaClient)
+	if err != nil {
+		t.Fatalf("error getting oktaClient: %v", err)
+	}
+	err = r.Delete(ctx, oktaClient)
+	if err != nil {
+		t.Fatalf("error deleting oktaClient: %v", err)
+	}
+	_, err = r.Reconcile(ctx, requests[0])
+	if err != nil {
+		t.Fatalf("error reconciling deleted oktaClient: %v", err)
+	}
+
+	oktaAPI := orgs.orgs[orgURL]
+	if len(oktaAPI.trustedOrigins) != 1 || oktaAPI.trustedOriginsDeleted != 0 {
+		t.Errorf("got origins %v and %d deletions, wanted the shared origin to be kept", oktaAPI.trustedOrigins, oktaAPI.trustedOriginsDeleted)
+	}
+}
+
+func TestDeleteTrustedOriginsDeactivate(t *testing.T) {
+	t.Parallel()
+	oktaAPI := newOktaMock()
+	oktaAPI.addTrustedOrigin("a")
+	oktaAPI.addTrustedOrigin("b")
+
+	err := deleteTrustedOrigins(&testToClient, context.Background(), newTestClient(), v1alpha1.DeletionPolicyDeactivate, oktaAPI)
+	if err != nil {
+		t.Errorf("error deactivating trusted origins: %v", err)
+	}
+	if oktaAPI.trustedOriginsDeactivated != 2 || oktaAPI.trustedOriginsDeleted != 0 {
+		t.Errorf("got %d deactivations and %d deletions, wanted %d and %d", oktaAPI.trustedOriginsDeactivated, oktaAPI.trustedOriginsDeleted, 2, 0)
+	}
+}
+
+func TestDeleteTrustedOriginsOrphan(t *testing.T) {
+	t.Parallel()
+	oktaAPI := newOktaMock()
+	oktaAPI.addTrustedOrigin("a")
+	oktaAPI.addTrustedOrigin("b")
+
+	err := deleteTrustedOrigins(&testToClient, context.Background(), newTestClient(), v1alpha1.DeletionPolicyOrphan, oktaAPI)
+	if err != nil {
+		t.Errorf("error orphaning trusted origins: %v", err)
+	}
+	if oktaAPI.trustedOriginsDeactivated != 0 || oktaAPI.trustedOriginsDeleted != 0 || len(oktaAPI.trustedOrigins) != 2 {
+		t.Errorf("got %d deactivations and %d deletions, wanted none", oktaAPI.trustedOriginsDeactivated, oktaAPI.trustedOriginsDeleted)
 	}
 }
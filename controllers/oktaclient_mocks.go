@@ -2,26 +2,22 @@ package controllers
 
 import (
 	"context"
+	"fmt"
 	"github.com/jaconi-io/okta-operator/api/v1alpha1"
 	"github.com/jaconi-io/okta-operator/okta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	controllerruntime "sigs.k8s.io/controller-runtime"
-	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"slices"
+	"sync"
 )
 
-type stringSlice []string
-
-var testOktaClients = make(map[string]*okta.Application)
-var testTrustedOrigins = stringSlice{}
-var appsCreated = 0
-var appsDeleted = 0
-var trustedOriginsCreated = 0
-var trustedOriginsDeleted = 0
-
 var testAppClient = v1alpha1.OktaClient{
-	TypeMeta:   metav1.TypeMeta{},
-	ObjectMeta: metav1.ObjectMeta{},
+	TypeMeta: metav1.TypeMeta{},
+	ObjectMeta: metav1.ObjectMeta{
+		Name:      "test-client",
+		Namespace: "default",
+	},
 	Spec: v1alpha1.OktaClientSpec{
 		Name: "test-client",
 	},
@@ -34,116 +30,293 @@ var testApp = okta.Application{
 	ClientSecret: "secret",
 }
 
-var testRequest = controllerruntime.Request{}
-
-func deleteAppMock(app *okta.Application) error {
-	appsDeleted++
-	delete(testOktaClients, app.ID)
-	return nil
+var testRequest = controllerruntime.Request{
+	NamespacedName: types.NamespacedName{Name: "test-client", Namespace: "default"},
 }
 
-func appCreatorMock(label string, clientUri string, redirectUris []string, postLogoutRedirectUris []string) (*okta.Application, error) {
-	appsCreated++
-	return addTestApplication(label, clientUri, redirectUris, postLogoutRedirectUris)
+var testToClient = v1alpha1.OktaClient{
+	TypeMeta: metav1.TypeMeta{},
+	ObjectMeta: metav1.ObjectMeta{
+		Name:      "test-to-client",
+		Namespace: "default",
+	},
+	Spec: v1alpha1.OktaClientSpec{
+		TrustedOrigins: []string{
+			"a", "b",
+		},
+	},
+	Status: v1alpha1.OktaClientStatus{},
 }
 
-func addTestApplication(label string, clientUri string, redirectUris []string, postLogoutRedirectUris []string) (*okta.Application, error) {
-	testOktaClients[label] = &testApp
-	return &testApp, nil
+// oktaMock implements okta.API in memory for unit tests. The envtest suite uses oktatest.Server instead.
+type oktaMock struct {
+	mu sync.Mutex
+
+	applications   map[string]*okta.Application
+	trustedOrigins []string
+	clientSecrets  []okta.ClientSecret
+
+	// groupAssignments maps application IDs to the groups they are assigned to.
+	groupAssignments map[string][]string
+
+	appsCreated               int
+	appsUpdated               int
+	appsDeleted               int
+	appsDeactivated           int
+	trustedOriginsCreated     int
+	trustedOriginsDeleted     int
+	trustedOriginsDeactivated int
+	clientSecretsCreated      int
+	clientSecretsDeleted      int
+	secretsRotated            int
+
+	// lookups counts the trusted origin, application and group assignment lookups.
+	lookups int
+
+	// pingErr is returned by Ping, e.g. to simulate revoked credentials.
+	pingErr error
+
+	// orgURL is the URL of the Okta organization. Defaults to https://example.okta.com.
+	orgURL string
 }
 
-func getAppByLabelMock(label string) (*okta.Application, error) {
-	return testOktaClients[label], nil
+var _ okta.API = &oktaMock{}
+
+func newOktaMock() *oktaMock {
+	return &oktaMock{applications: make(map[string]*okta.Application), groupAssignments: make(map[string][]string)}
 }
 
-func createOrUpdateSecretMock(ctx context.Context, c client.Client, obj client.Object, f controllerutil.MutateFn) (controllerutil.OperationResult, error) {
-	return controllerutil.OperationResultNone, nil
+// addApplication adds an application with the given settings. Its ID is the label of the application.
+func (m *oktaMock) addApplication(settings okta.ApplicationSettings) *okta.Application {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.add(settings)
 }
 
-func newSecretMock(clientID string) (string, error) {
-	return "secret", nil
+func (m *oktaMock) add(settings okta.ApplicationSettings) *okta.Application {
+	app := testApp
+	app.ID = settings.Label
+	app.ApplicationSettings = settings
+	m.applications[settings.Label] = &app
+	return &app
 }
 
-var testToClient = v1alpha1.OktaClient{
-	TypeMeta:   metav1.TypeMeta{},
-	ObjectMeta: metav1.ObjectMeta{},
-	Spec: v1alpha1.OktaClientSpec{
-		TrustedOrigins: []string{
-			"a", "b",
-		},
-	},
-	Status: v1alpha1.OktaClientStatus{},
+// addTrustedOrigin adds a trusted origin.
+func (m *oktaMock) addTrustedOrigin(origin string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.trustedOrigins = append(m.trustedOrigins, origin)
 }
 
-func addTrustedOriginMock(origin string) error {
-	trustedOriginsCreated++
-	return addTestTrustedOrigin(origin)
+func (m *oktaMock) OrgURL() string {
+	if m.orgURL != "" {
+		return m.orgURL
+	}
+	return "https://example.okta.com"
 }
 
-func addTestTrustedOrigin(origin string) error {
-	testTrustedOrigins = append(testTrustedOrigins, origin)
-	return nil
+func (m *oktaMock) Ping(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.pingErr
 }
 
-func removeIndex(s []string, index int) []string {
-	return append(s[:index], s[index+1:]...)
+// setPingErr sets the error returned by Ping.
+func (m *oktaMock) setPingErr(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.pingErr = err
 }
 
-func pos(s []string, value string) int {
-	for p, v := range s {
-		if v == value {
-			return p
+func (m *oktaMock) GetApplicationByID(ctx context.Context, id string) (*okta.Application, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lookups++
+	for _, app := range m.applications {
+		if app.ID == id {
+			return app, nil
 		}
 	}
-	return -1
+	return nil, nil
 }
 
-func deleteTrustedOriginMock(origin string) error {
-	trustedOriginsDeleted++
-	pos := pos(testTrustedOrigins, origin)
-	if pos >= 0 {
-		testTrustedOrigins = removeIndex(testTrustedOrigins, pos)
+func (m *oktaMock) GetApplicationByLabel(ctx context.Context, label string) (*okta.Application, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.applications[label], nil
+}
+
+func (m *oktaMock) CreateApplication(ctx context.Context, settings okta.ApplicationSettings) (*okta.Application, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.appsCreated++
+	return m.add(settings), nil
+}
+
+func (m *oktaMock) UpdateApplication(ctx context.Context, app *okta.Application, settings okta.ApplicationSettings) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.appsUpdated++
+	app.ApplicationSettings = settings
+	return nil
+}
+
+func (m *oktaMock) DeactivateApplication(ctx context.Context, app *okta.Application) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.appsDeactivated++
+	return nil
+}
+
+func (m *oktaMock) DeleteApplication(ctx context.Context, app *okta.Application) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.appsDeleted++
+	delete(m.applications, app.ID)
+	return nil
+}
+
+func (m *oktaMock) CreateApplicationGroupAssignment(ctx context.Context, app *okta.Application, groupID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !slices.Contains(m.groupAssignments[app.ID], groupID) {
+		m.groupAssignments[app.ID] = append(m.groupAssignments[app.ID], groupID)
 	}
 	return nil
 }
 
-func isTrustedMock(origin string) (bool, error) {
-	for _, s := range testTrustedOrigins {
-		if origin == s {
-			return true, nil
-		}
+func (m *oktaMock) IsApplicationGroupAssigned(ctx context.Context, app *okta.Application, groupID string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lookups++
+	return slices.Contains(m.groupAssignments[app.ID], groupID), nil
+}
+
+func (m *oktaMock) NewSecret(ctx context.Context, clientID string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.secretsRotated++
+	return "secret", nil
+}
+
+func (m *oktaMock) ListClientSecrets(ctx context.Context, app *okta.Application) ([]okta.ClientSecret, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return slices.Clone(m.clientSecrets), nil
+}
+
+func (m *oktaMock) CreateClientSecret(ctx context.Context, app *okta.Application) (*okta.ClientSecret, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.clientSecretsCreated++
+	secret := okta.ClientSecret{
+		ID:     fmt.Sprintf("secret-%d", m.clientSecretsCreated),
+		Secret: fmt.Sprintf("secret-value-%d", m.clientSecretsCreated),
+		Status: "ACTIVE",
 	}
-	return false, nil
+	m.clientSecrets = append(m.clientSecrets, secret)
+	return &secret, nil
+}
+
+func (m *oktaMock) DeleteClientSecret(ctx context.Context, app *okta.Application, secretID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.clientSecretsDeleted++
+	m.clientSecrets = slices.DeleteFunc(m.clientSecrets, func(secret okta.ClientSecret) bool {
+		return secret.ID == secretID
+	})
+	return nil
 }
 
-func getSecretMock(k8sClient client.Client, ctx context.Context, req controllerruntime.Request, secretName string) error {
+func (m *oktaMock) IsTrustedOrigin(ctx context.Context, origin string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lookups++
+	return slices.Contains(m.trustedOrigins, origin), nil
+}
+
+func (m *oktaMock) CreateTrustedOrigin(ctx context.Context, origin string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.trustedOriginsCreated++
+	m.trustedOrigins = append(m.trustedOrigins, origin)
+	return nil
+}
+
+func (m *oktaMock) DeleteTrustedOrigin(ctx context.Context, origin string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.trustedOriginsDeleted++
+	m.trustedOrigins = slices.DeleteFunc(m.trustedOrigins, func(o string) bool {
+		return o == origin
+	})
+	return nil
+}
+
+func (m *oktaMock) DeactivateTrustedOrigin(ctx context.Context, origin string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.trustedOriginsDeactivated++
 	return nil
 }
 
-func reset() {
-	testOktaClients = make(map[string]*okta.Application)
-	testTrustedOrigins = []string{}
-	isTrustedOrigin = isTrustedMock
-	createTrustedOrigin = addTrustedOriginMock
-	deleteTrustedOrigin = deleteTrustedOriginMock
-	getAppByLabel = getAppByLabelMock
-	deleteApp = deleteAppMock
-	createApp = appCreatorMock
-	newSecret = newSecretMock
-	appsCreated = 0
-	appsDeleted = 0
-	trustedOriginsCreated = 0
-	trustedOriginsDeleted = 0
-}
-
-func resetToLocal() {
-	reset()
-	createOrUpdateSecret = createOrUpdateSecretMock
-	getSecret = getSecretMock
-}
-
-func resetToCluster() {
-	reset()
-	createOrUpdateSecret = controllerutil.CreateOrUpdate
-	getSecret = getSecretImpl
+func (m *oktaMock) GetAuthorizationServerMetadata(ctx context.Context, authorizationServerID string) (*okta.AuthorizationServerMetadata, error) {
+	issuer := m.OrgURL()
+	if authorizationServerID != "" {
+		issuer = fmt.Sprintf("%s/oauth2/%s", issuer, authorizationServerID)
+	}
+	return &okta.AuthorizationServerMetadata{
+		DiscoveryURL:          issuer + "/.well-known/openid-configuration",
+		Issuer:                issuer,
+		AuthorizationEndpoint: issuer + "/v1/authorize",
+		TokenEndpoint:         issuer + "/v1/token",
+		JWKSURI:               issuer + "/v1/keys",
+	}, nil
+}
+
+// orgsMock implements okta.ClientFactory with an oktaMock per Okta organization for unit tests.
+type orgsMock struct {
+	mu sync.Mutex
+
+	orgs        map[string]*oktaMock
+	credentials map[string]okta.Credentials
+}
+
+var _ okta.ClientFactory = &orgsMock{}
+
+func newOrgsMock() *orgsMock {
+	return &orgsMock{orgs: make(map[string]*oktaMock), credentials: make(map[string]okta.Credentials)}
+}
+
+// Client returns the oktaMock of the organization and records the credentials it has been requested with.
+func (m *orgsMock) Client(ctx context.Context, orgURL string, credentials okta.Credentials) (okta.API, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.orgs[orgURL]; !ok {
+		m.orgs[orgURL] = newOktaMock()
+		m.orgs[orgURL].orgURL = orgURL
+	}
+	m.credentials[orgURL] = credentials
+	return m.orgs[orgURL], nil
 }
@@ -19,20 +19,28 @@ package controllers
 import (
 	"context"
 	"math/rand"
+	"net/http/httptest"
 	"path/filepath"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"testing"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	oktav1alpha1 "github.com/jaconi-io/okta-operator/api/v1alpha1"
+	"github.com/jaconi-io/okta-operator/okta"
+	"github.com/jaconi-io/okta-operator/okta/oktatest"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -43,10 +51,34 @@ var (
 	cfg       *rest.Config
 	k8sClient client.Client // You'll be using this client in your tests.
 	testEnv   *envtest.Environment
+	testOkta  = oktatest.NewServer("token") // The fake Okta organization used by the manager.
 	ctx       context.Context
 	cancel    context.CancelFunc
 )
 
+// newTestClient returns a fake Kubernetes client containing the given objects for unit tests.
+func newTestClient(objects ...client.Object) client.Client {
+	testScheme := runtime.NewScheme()
+	utilruntime.Must(scheme.AddToScheme(testScheme))
+	utilruntime.Must(oktav1alpha1.AddToScheme(testScheme))
+
+	return fake.NewClientBuilder().
+		WithScheme(testScheme).
+		WithObjects(objects...).
+		WithIndex(&oktav1alpha1.OktaClient{}, trustedOriginsIndex, indexTrustedOrigins).
+		WithIndex(&oktav1alpha1.OktaClient{}, applicationIDIndex, indexApplicationID).
+		WithStatusSubresource(&oktav1alpha1.OktaClient{}).
+		Build()
+}
+
+// newTestSecret returns the secret of the OktaClient used by unit tests with the given data.
+func newTestSecret(data map[string][]byte) *core.Secret {
+	return &core.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: testRequest.Name, Namespace: testRequest.Namespace},
+		Data:       data,
+	}
+}
+
 func TestAPIs(t *testing.T) {
 	RegisterFailHandler(Fail)
 
@@ -83,9 +115,21 @@ var _ = BeforeSuite(func() {
 	})
 	Expect(err).ToNot(HaveOccurred())
 
+	oktaServer := httptest.NewTLSServer(testOkta)
+	DeferCleanup(oktaServer.Close)
+
+	oktaAPI, err := okta.NewClient(ctx, okta.Config{
+		OrgURL:      oktaServer.URL,
+		Credentials: okta.Credentials{Token: "token"},
+		HTTPClient:  oktaServer.Client(),
+	})
+	Expect(err).ToNot(HaveOccurred())
+
 	err = (&OktaClientReconciler{
-		Client: k8sManager.GetClient(),
-		Scheme: k8sManager.GetScheme(),
+		Client:   k8sManager.GetClient(),
+		Scheme:   k8sManager.GetScheme(),
+		Recorder: k8sManager.GetEventRecorderFor("oktaClient"),
+		Okta:     oktaAPI,
 	}).SetupWithManager(k8sManager)
 	Expect(err).ToNot(HaveOccurred())
 
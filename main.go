@@ -17,8 +17,14 @@ limitations under the License.
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
+	"fmt"
+	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -28,13 +34,16 @@ import (
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	oktav1alpha1 "github.com/jaconi-io/okta-operator/api/v1alpha1"
 	"github.com/jaconi-io/okta-operator/controllers"
+	"github.com/jaconi-io/okta-operator/okta"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -55,12 +64,48 @@ func main() {
 	var enableLeaderElection bool
 	var probeAddr string
 	var groupID string
+	var defaultDeletionPolicy string
+	var oktaRequestTimeout time.Duration
+	var resyncInterval time.Duration
+	var eventHookPort int
+	var eventHookCertDir string
+	var oktaClientID string
+	var oktaPrivateKeyFile string
+	var oktaPrivateKeyID string
+	var oktaScopes string
+	var oktaTokenFile string
+	var oktaCredentialsReloadInterval time.Duration
+	var oktaProbeInterval time.Duration
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
 	flag.StringVar(&groupID, "group-id", "", "The group ID of the group the applications created by this operator will be assigned to.")
+	flag.StringVar(&defaultDeletionPolicy, "default-deletion-policy", string(oktav1alpha1.DeletionPolicyDelete),
+		"The deletion policy of OktaClients without one. One of Delete, Deactivate or Orphan.")
+	flag.DurationVar(&oktaRequestTimeout, "okta-request-timeout", 30*time.Second,
+		"The timeout of a single request to the Okta API, including retries of transient errors. Zero disables the timeout.")
+	flag.DurationVar(&resyncInterval, "resync-interval", 10*time.Minute,
+		"The interval after which OktaClients are reconciled again to detect changes made in Okta. Zero disables the resync.")
+	flag.IntVar(&eventHookPort, "event-hook-port", 0,
+		"The port the Okta event hook endpoint binds to. Zero disables the endpoint.")
+	flag.StringVar(&eventHookCertDir, "event-hook-cert-dir", "",
+		"The directory containing tls.crt and tls.key of the Okta event hook endpoint. Defaults to the one of the webhook server.")
+	flag.StringVar(&oktaClientID, "okta-client-id", "",
+		"The client ID of the Okta service application the operator authenticates as. Defaults to API token authentication.")
+	flag.StringVar(&oktaPrivateKeyFile, "okta-private-key-file", "",
+		"The file containing the PEM encoded private key of the Okta service application, e.g. mounted from a secret.")
+	flag.StringVar(&oktaPrivateKeyID, "okta-private-key-id", "",
+		"The ID of the public key registered with the Okta service application.")
+	flag.StringVar(&oktaTokenFile, "okta-token-file", "",
+		"The file containing the Okta API token, e.g. mounted from a secret. Defaults to the OKTA_CLIENT_TOKEN environment variable.")
+	flag.DurationVar(&oktaCredentialsReloadInterval, "okta-credentials-reload-interval", 10*time.Second,
+		"The interval after which the Okta API token and private key files are read again to pick up rotated credentials.")
+	flag.DurationVar(&oktaProbeInterval, "okta-probe-interval", time.Minute,
+		"The interval after which the default Okta organization is called again to verify that it is reachable. Zero disables the probe.")
+	flag.StringVar(&oktaScopes, "okta-scopes", strings.Join(okta.DefaultScopes, ","),
+		"The comma separated scopes requested for the access tokens of the Okta service application.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -69,6 +114,13 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	switch oktav1alpha1.DeletionPolicy(defaultDeletionPolicy) {
+	case oktav1alpha1.DeletionPolicyDelete, oktav1alpha1.DeletionPolicyDeactivate, oktav1alpha1.DeletionPolicyOrphan:
+	default:
+		setupLog.Error(fmt.Errorf("invalid deletion policy %q", defaultDeletionPolicy), "unable to parse flags")
+		os.Exit(1)
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme: scheme,
 		Metrics: metricsserver.Options{
@@ -86,15 +138,125 @@ func main() {
 		os.Exit(1)
 	}
 
+	// The client of the default Okta organization is configured by environment variables, see README.md. Credentials
+	// read from files are reloaded, once they change. Without the client, OktaClients must reference an OktaOrg or
+	// ClusterOktaOrg. Contradicting flags are an error. Credentials that cannot be loaded are reported by the readiness
+	// check instead, so they can be fixed without restarting the operator.
+	switch {
+	case oktaClientID != "" && oktaPrivateKeyFile == "":
+		setupLog.Error(errors.New("--okta-client-id requires --okta-private-key-file"), "unable to parse flags")
+		os.Exit(1)
+	case oktaClientID == "" && oktaPrivateKeyFile != "":
+		setupLog.Error(errors.New("--okta-private-key-file requires --okta-client-id"), "unable to parse flags")
+		os.Exit(1)
+	case oktaClientID != "" && oktaTokenFile != "":
+		setupLog.Error(errors.New("--okta-token-file cannot be combined with --okta-client-id"), "unable to parse flags")
+		os.Exit(1)
+	}
+
+	oktaConfig := okta.Config{RequestTimeout: oktaRequestTimeout}
+	defaultConfig := oktaConfig
+	if oktaClientID != "" {
+		defaultConfig.Credentials = okta.Credentials{
+			ClientID:     oktaClientID,
+			PrivateKeyID: oktaPrivateKeyID,
+			Scopes:       strings.Split(oktaScopes, ","),
+		}
+	}
+	var oktaAPI okta.API
+	if oktaTokenFile != "" || oktaPrivateKeyFile != "" {
+		files := okta.CredentialFiles{TokenFile: oktaTokenFile, PrivateKeyFile: oktaPrivateKeyFile}
+		reloadingClient := okta.NewReloadingClient(context.Background(), defaultConfig, files, oktaCredentialsReloadInterval)
+		if err := reloadingClient.Check(nil); err != nil {
+			setupLog.Error(err, "unable to create client of the default Okta organization, not ready until the credentials load")
+		}
+		if err := mgr.Add(reloadingClient); err != nil {
+			setupLog.Error(err, "unable to add Okta credentials reloader")
+			os.Exit(1)
+		}
+		if err := mgr.AddReadyzCheck("okta-credentials", reloadingClient.Check); err != nil {
+			setupLog.Error(err, "unable to set up Okta credentials check")
+			os.Exit(1)
+		}
+		oktaAPI = reloadingClient
+	} else if oktaClientID != "" || okta.IsConfigured() {
+		defaultClient, err := okta.NewClient(context.Background(), defaultConfig)
+		if err != nil {
+			// Credentials read from the environment are not reloaded. The operator stays unready until it is restarted
+			// with working ones.
+			setupLog.Error(err, "unable to create client of the default Okta organization")
+			credentialsErr := fmt.Errorf("failed to load Okta credentials: %w", err)
+			if err := mgr.AddReadyzCheck("okta-credentials", func(*http.Request) error { return credentialsErr }); err != nil {
+				setupLog.Error(err, "unable to set up Okta credentials check")
+				os.Exit(1)
+			}
+		} else {
+			oktaAPI = defaultClient
+		}
+	} else {
+		setupLog.Info("no default Okta organization configured, OktaClients must reference an OktaOrg or ClusterOktaOrg")
+	}
+
+	// The Okta event hook triggers reconciliations, once OktaClients are changed in Okta. The secret authenticating Okta
+	// is configured by an environment variable, see README.md. Without it, anyone reaching the endpoint could trigger
+	// reconciliations and thereby requests to Okta.
+	var events <-chan event.GenericEvent
+	if eventHookPort != 0 {
+		eventHookSecret := os.Getenv("OKTA_EVENT_HOOK_SECRET")
+		if eventHookSecret == "" {
+			setupLog.Error(errors.New("OKTA_EVENT_HOOK_SECRET is not set"), "unable to set up Okta event hook")
+			os.Exit(1)
+		}
+		eventHook := controllers.NewEventHook(mgr.GetClient(), oktaAPI, eventHookSecret)
+		eventHookServer := webhook.NewServer(webhook.Options{
+			Port:    eventHookPort,
+			CertDir: eventHookCertDir,
+		})
+		eventHookServer.Register(controllers.EventHookPath, eventHook)
+		if err = mgr.Add(eventHookServer); err != nil {
+			setupLog.Error(err, "unable to add Okta event hook server")
+			os.Exit(1)
+		}
+		events = eventHook.Events()
+	}
+
+	// The probe verifies that the default Okta organization is reachable and accepts the credentials. The operator is
+	// not ready otherwise.
+	var probe *controllers.OktaProbe
+	if oktaAPI != nil && oktaProbeInterval > 0 {
+		probe = controllers.NewOktaProbe(oktaAPI, mgr.GetClient(), oktaProbeInterval)
+		if err = mgr.Add(probe); err != nil {
+			setupLog.Error(err, "unable to add Okta probe")
+			os.Exit(1)
+		}
+		if err = mgr.AddReadyzCheck("okta", probe.Check); err != nil {
+			setupLog.Error(err, "unable to set up Okta ready check")
+			os.Exit(1)
+		}
+	}
+
 	if err = (&controllers.OktaClientReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("oktaClient"),
+		Okta:     oktaAPI,
+		Orgs:     okta.NewClientCache(oktaConfig),
+
+		Events:                events,
+		Probe:                 probe,
+		ResyncInterval:        resyncInterval,
+		DefaultDeletionPolicy: oktav1alpha1.DeletionPolicy(defaultDeletionPolicy),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "OktaClient")
 		os.Exit(1)
 	}
 	//+kubebuilder:scaffold:builder
 
+	if err := metrics.Registry.Register(controllers.NewManagedResourcesCollector(mgr.GetClient(), oktaAPI)); err != nil {
+		setupLog.Error(err, "unable to register metrics of managed Okta resources")
+		os.Exit(1)
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
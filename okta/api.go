@@ -0,0 +1,40 @@
+package okta
+
+import "context"
+
+// API of an Okta organization, as used by the operator. Client implements it using the Okta management API. Calls
+// are cancelled, once their context is done.
+type API interface {
+	// OrgURL returns the URL of the Okta organization.
+	OrgURL() string
+
+	// Ping performs a cheap authenticated call, verifying that the Okta organization is reachable with the configured
+	// credentials.
+	Ping(ctx context.Context) error
+
+	GetApplicationByID(ctx context.Context, id string) (*Application, error)
+	GetApplicationByLabel(ctx context.Context, label string) (*Application, error)
+	CreateApplication(ctx context.Context, settings ApplicationSettings) (*Application, error)
+	UpdateApplication(ctx context.Context, app *Application, settings ApplicationSettings) error
+	DeactivateApplication(ctx context.Context, app *Application) error
+	DeleteApplication(ctx context.Context, app *Application) error
+	CreateApplicationGroupAssignment(ctx context.Context, app *Application, groupID string) error
+	IsApplicationGroupAssigned(ctx context.Context, app *Application, groupID string) (bool, error)
+
+	NewSecret(ctx context.Context, clientID string) (string, error)
+	ListClientSecrets(ctx context.Context, app *Application) ([]ClientSecret, error)
+	CreateClientSecret(ctx context.Context, app *Application) (*ClientSecret, error)
+	DeleteClientSecret(ctx context.Context, app *Application, secretID string) error
+
+	IsTrustedOrigin(ctx context.Context, origin string) (bool, error)
+	CreateTrustedOrigin(ctx context.Context, origin string) error
+	DeleteTrustedOrigin(ctx context.Context, origin string) error
+	DeactivateTrustedOrigin(ctx context.Context, origin string) error
+
+	GetAuthorizationServerMetadata(ctx context.Context, authorizationServerID string) (*AuthorizationServerMetadata, error)
+}
+
+// ClientFactory returns the API of an Okta organization by its URL and credentials. ClientCache implements it.
+type ClientFactory interface {
+	Client(ctx context.Context, orgURL string, credentials Credentials) (API, error)
+}
@@ -1,35 +1,45 @@
 package okta
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"github.com/okta/okta-sdk-golang/v2/okta"
 	"github.com/okta/okta-sdk-golang/v2/okta/query"
 	"io"
-	"strings"
 )
 
 // Application described an Okta application without exposing Okta types outside of this package.
 type Application struct {
+	ApplicationSettings
 	ID           string
 	ClientID     string
 	ClientSecret string
 }
 
-func CreateApplicationGroupAssignment(app *Application, groupID string) error {
-	ctx, client := getContextAndClient()
+// ApplicationSettings describe the label and OAuth client settings of an Okta application.
+type ApplicationSettings struct {
+	Label                   string
+	ClientUri               string
+	RedirectUris            []string
+	PostLogoutRedirectUris  []string
+	ApplicationType         string
+	GrantTypes              []string
+	ResponseTypes           []string
+	TokenEndpointAuthMethod string
 
-	assignments, _, err := client.Application.GetApplicationGroupAssignment(ctx, app.ID, groupID, nil)
+	// Keys are the public keys used for private key JWT client authentication.
+	Keys []JSONWebKey
+}
+
+func (c *Client) CreateApplicationGroupAssignment(ctx context.Context, app *Application, groupID string) error {
+	assigned, err := c.IsApplicationGroupAssigned(ctx, app, groupID)
 	if err != nil {
-		var e *okta.Error
-		if !(errors.As(err, &e) && strings.HasPrefix(e.ErrorSummary, "Not found")) {
-			return fmt.Errorf("failed to get application group assignment for application %q and group %q: %w", app.ID, groupID, err)
-		}
+		return err
 	}
 
-	if assignments == nil {
-		_, _, err := client.Application.CreateApplicationGroupAssignment(ctx, app.ID, groupID, okta.ApplicationGroupAssignment{})
+	if !assigned {
+		_, _, err := c.client.Application.CreateApplicationGroupAssignment(ctx, app.ID, groupID, okta.ApplicationGroupAssignment{})
 		if err != nil {
 			return fmt.Errorf("failed to create application group assignment for application %q and group %q: %w", app.ID, groupID, err)
 		}
@@ -38,92 +48,191 @@ func CreateApplicationGroupAssignment(app *Application, groupID string) error {
 	return nil
 }
 
-func GetApplicationByLabel(label string) (*Application, error) {
-	ctx, client := getContextAndClient()
+// IsApplicationGroupAssigned returns true, if the application is assigned to the group.
+func (c *Client) IsApplicationGroupAssigned(ctx context.Context, app *Application, groupID string) (bool, error) {
+	_, _, err := c.client.Application.GetApplicationGroupAssignment(ctx, app.ID, groupID, nil)
+	if err != nil {
+		if isNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get application group assignment for application %q and group %q: %w", app.ID, groupID, err)
+	}
+	return true, nil
+}
+
+// GetApplicationByID returns the application with the given ID or nil, if no such application exists.
+func (c *Client) GetApplicationByID(ctx context.Context, id string) (*Application, error) {
+	client := c.client
 
-	filter := query.NewQueryParams(query.WithQ(label), query.WithLimit(1))
-	apps, resp, err := client.Application.ListApplications(ctx, filter)
+	_, resp, err := client.Application.GetApplication(ctx, id, okta.NewOpenIdConnectApplication(), nil)
 	if err != nil {
-		return nil, fmt.Errorf("error getting client ID for label %q; API error: %w", label, err)
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting application %q: %w", id, err)
 	}
 
-	// Not found.
-	if len(apps) == 0 {
-		return nil, nil
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error getting application %q; error reading response body: %w", id, err)
+	}
+
+	app, err := parseApplication(body)
+	if err != nil {
+		return nil, fmt.Errorf("error getting application %q; error parsing response body: %w", id, err)
 	}
 
-	var oidcApps []*okta.OpenIdConnectApplication
+	return app, nil
+}
+
+// GetApplicationByLabel returns the application with exactly the given label or nil, if no such application exists.
+// If more than one application has the label, an error is returned.
+func (c *Client) GetApplicationByLabel(ctx context.Context, label string) (*Application, error) {
+	client := c.client
+
+	// The q parameter performs a prefix search on the label and name of an application. Filter the result for an exact
+	// match.
+	filter := query.NewQueryParams(query.WithQ(label), query.WithLimit(200))
+	_, resp, err := client.Application.ListApplications(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("error getting client ID for label %q; API error: %w", label, err)
+	}
+
+	var page []json.RawMessage
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("error getting client ID for label %q; error reading response body: %w", label, err)
 	}
 
-	err = json.Unmarshal(body, &oidcApps)
+	err = json.Unmarshal(body, &page)
 	if err != nil {
 		return nil, fmt.Errorf("error getting client ID for label %q; error parsing response body: %w", label, err)
 	}
 
-	return &Application{
-		ID:           oidcApps[0].Id,
-		ClientID:     oidcApps[0].Credentials.OauthClient.ClientId,
-		ClientSecret: oidcApps[0].Credentials.OauthClient.ClientSecret, // This is not returned by the Okta API!
-	}, nil
+	// The exact match may be on any page of the prefix matches. Follow the next link until all of them have been read.
+	var matches []*Application
+	for {
+		for _, body := range page {
+			app, err := parseApplication(body)
+			if err != nil {
+				return nil, fmt.Errorf("error getting client ID for label %q; error parsing response body: %w", label, err)
+			}
+			if app.Label == label {
+				matches = append(matches, app)
+			}
+		}
+
+		if !resp.HasNextPage() {
+			break
+		}
+		page = nil
+		resp, err = resp.Next(ctx, &page)
+		if err != nil {
+			return nil, fmt.Errorf("error getting client ID for label %q; API error: %w", label, err)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, nil
+	case 1:
+		return matches[0], nil // The client secret is not returned by the Okta API!
+	default:
+		return nil, fmt.Errorf("error getting client ID for label %q; found %d applications with this label", label, len(matches))
+	}
 }
 
 // CreateApplication in Okta and return it.
-func CreateApplication(label string, clientUri string, redirectUris []string, postLogoutRedirectUris []string) (*Application, error) {
-	ctx, client := getContextAndClient()
+func (c *Client) CreateApplication(ctx context.Context, settings ApplicationSettings) (*Application, error) {
+	client := c.client
 
 	app := okta.NewOpenIdConnectApplication()
-	app.Label = label
 	app.Credentials = &okta.OAuthApplicationCredentials{
 		OauthClient: &okta.ApplicationCredentialsOAuthClient{
-			AutoKeyRotation:         _true(),
-			TokenEndpointAuthMethod: "client_secret_post",
+			AutoKeyRotation: _true(),
 		},
 	}
-
-	responseType := okta.OAuthResponseType("code")
-	grantTypeRefreshToken := okta.OAuthGrantType("refresh_token")
-	grantTypeAuthorizationCode := okta.OAuthGrantType("authorization_code")
 	app.Settings = &okta.OpenIdConnectApplicationSettings{
 		OauthClient: &okta.OpenIdConnectApplicationSettingsClient{
-			ClientUri:              clientUri,
-			LogoUri:                "",
-			RedirectUris:           redirectUris,
-			PostLogoutRedirectUris: postLogoutRedirectUris,
-			ResponseTypes:          []*okta.OAuthResponseType{&responseType},
-			GrantTypes:             []*okta.OAuthGrantType{&grantTypeRefreshToken, &grantTypeAuthorizationCode},
-			ConsentMethod:          "REQUIRED",
-			ApplicationType:        "web",
+			LogoUri:       "",
+			ConsentMethod: "REQUIRED",
 		},
 	}
+	applySettings(app, settings)
 
-	_, resp, err := client.Application.CreateApplication(ctx, app, nil)
+	reqBody, err := applicationBody(app, settings.Keys)
+	if err != nil {
+		return nil, fmt.Errorf("error creating app %q: %w", settings.Label, err)
+	}
+
+	rq := client.CloneRequestExecutor()
+	req, err := rq.WithAccept("application/json").WithContentType("application/json").NewRequest("POST", "/api/v1/apps", reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("error creating app %q: %w", settings.Label, err)
+	}
+
+	var body json.RawMessage
+	_, err = rq.Do(ctx, req, &body)
 	if err != nil {
 		return nil, fmt.Errorf("error creating app: %w", err)
 	}
 
-	var oidcApp *okta.OpenIdConnectApplication
-	body, err := io.ReadAll(resp.Body)
+	createdApp, err := parseApplication(body)
 	if err != nil {
-		return nil, fmt.Errorf("error creating app %q; error reading response body: %w", label, err)
+		return nil, fmt.Errorf("error creating app %q; error parsing response body: %w", settings.Label, err)
 	}
 
-	err = json.Unmarshal(body, &oidcApp)
+	return createdApp, nil
+}
+
+// UpdateApplication in Okta to match the given settings.
+func (c *Client) UpdateApplication(ctx context.Context, app *Application, settings ApplicationSettings) error {
+	client := c.client
+
+	oidcApp := okta.NewOpenIdConnectApplication()
+	_, _, err := client.Application.GetApplication(ctx, app.ID, oidcApp, nil)
 	if err != nil {
-		return nil, fmt.Errorf("error creating app %q; error parsing response body: %w", label, err)
+		return fmt.Errorf("error getting application %q for update: %w", app.ID, err)
 	}
 
-	return &Application{
-		ID:           oidcApp.Id,
-		ClientID:     oidcApp.Credentials.OauthClient.ClientId,
-		ClientSecret: oidcApp.Credentials.OauthClient.ClientSecret,
-	}, err
+	applySettings(oidcApp, settings)
+
+	reqBody, err := applicationBody(oidcApp, settings.Keys)
+	if err != nil {
+		return fmt.Errorf("error updating application %q: %w", app.ID, err)
+	}
+
+	rq := client.CloneRequestExecutor()
+	req, err := rq.WithAccept("application/json").WithContentType("application/json").NewRequest("PUT", fmt.Sprintf("/api/v1/apps/%s", app.ID), reqBody)
+	if err != nil {
+		return fmt.Errorf("error updating application %q: %w", app.ID, err)
+	}
+
+	var body json.RawMessage
+	_, err = rq.Do(ctx, req, &body)
+	if err != nil {
+		return fmt.Errorf("error updating application %q: %w", app.ID, err)
+	}
+
+	app.ApplicationSettings = settings
+
+	return nil
+}
+
+// DeactivateApplication in Okta without deleting it.
+func (c *Client) DeactivateApplication(ctx context.Context, app *Application) error {
+	client := c.client
+
+	_, err := client.Application.DeactivateApplication(ctx, app.ID)
+	if err != nil {
+		return fmt.Errorf("error deactivating application %q: %w", app.ID, err)
+	}
+
+	return nil
 }
 
-func DeleteApplication(app *Application) error {
-	ctx, client := getContextAndClient()
+func (c *Client) DeleteApplication(ctx context.Context, app *Application) error {
+	client := c.client
 
 	_, err := client.Application.DeactivateApplication(ctx, app.ID)
 	if err != nil {
@@ -137,3 +246,69 @@ func DeleteApplication(app *Application) error {
 
 	return nil
 }
+
+// toApplication converts an Okta OIDC application to an Application.
+func toApplication(oidcApp *okta.OpenIdConnectApplication) *Application {
+	app := &Application{
+		ID: oidcApp.Id,
+	}
+	app.Label = oidcApp.Label
+
+	if oidcApp.Credentials != nil && oidcApp.Credentials.OauthClient != nil {
+		app.ClientID = oidcApp.Credentials.OauthClient.ClientId
+		app.ClientSecret = oidcApp.Credentials.OauthClient.ClientSecret
+		app.TokenEndpointAuthMethod = oidcApp.Credentials.OauthClient.TokenEndpointAuthMethod
+	}
+
+	if oidcApp.Settings != nil && oidcApp.Settings.OauthClient != nil {
+		app.ClientUri = oidcApp.Settings.OauthClient.ClientUri
+		app.RedirectUris = oidcApp.Settings.OauthClient.RedirectUris
+		app.PostLogoutRedirectUris = oidcApp.Settings.OauthClient.PostLogoutRedirectUris
+		app.ApplicationType = oidcApp.Settings.OauthClient.ApplicationType
+		for _, grantType := range oidcApp.Settings.OauthClient.GrantTypes {
+			app.GrantTypes = append(app.GrantTypes, string(*grantType))
+		}
+		for _, responseType := range oidcApp.Settings.OauthClient.ResponseTypes {
+			app.ResponseTypes = append(app.ResponseTypes, string(*responseType))
+		}
+	}
+
+	return app
+}
+
+// applySettings to an Okta OIDC application.
+func applySettings(oidcApp *okta.OpenIdConnectApplication, settings ApplicationSettings) {
+	if oidcApp.Credentials == nil {
+		oidcApp.Credentials = &okta.OAuthApplicationCredentials{}
+	}
+	if oidcApp.Credentials.OauthClient == nil {
+		oidcApp.Credentials.OauthClient = &okta.ApplicationCredentialsOAuthClient{}
+	}
+	if oidcApp.Settings == nil {
+		oidcApp.Settings = &okta.OpenIdConnectApplicationSettings{}
+	}
+	if oidcApp.Settings.OauthClient == nil {
+		oidcApp.Settings.OauthClient = &okta.OpenIdConnectApplicationSettingsClient{}
+	}
+
+	var grantTypes []*okta.OAuthGrantType
+	for _, grantType := range settings.GrantTypes {
+		g := okta.OAuthGrantType(grantType)
+		grantTypes = append(grantTypes, &g)
+	}
+
+	var responseTypes []*okta.OAuthResponseType
+	for _, responseType := range settings.ResponseTypes {
+		r := okta.OAuthResponseType(responseType)
+		responseTypes = append(responseTypes, &r)
+	}
+
+	oidcApp.Label = settings.Label
+	oidcApp.Credentials.OauthClient.TokenEndpointAuthMethod = settings.TokenEndpointAuthMethod
+	oidcApp.Settings.OauthClient.ClientUri = settings.ClientUri
+	oidcApp.Settings.OauthClient.RedirectUris = settings.RedirectUris
+	oidcApp.Settings.OauthClient.PostLogoutRedirectUris = settings.PostLogoutRedirectUris
+	oidcApp.Settings.OauthClient.ApplicationType = settings.ApplicationType
+	oidcApp.Settings.OauthClient.GrantTypes = grantTypes
+	oidcApp.Settings.OauthClient.ResponseTypes = responseTypes
+}
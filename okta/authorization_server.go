@@ -0,0 +1,58 @@
+package okta
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// AuthorizationServerMetadata describes the OpenID Connect discovery metadata of an Okta authorization server.
+type AuthorizationServerMetadata struct {
+	DiscoveryURL          string `json:"-"`
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+	EndSessionEndpoint    string `json:"end_session_endpoint"`
+}
+
+// GetAuthorizationServerMetadata returns the discovery metadata of the custom authorization server with the given ID
+// or of the org authorization server, if the ID is empty. The metadata is cached.
+func (c *Client) GetAuthorizationServerMetadata(ctx context.Context, authorizationServerID string) (*AuthorizationServerMetadata, error) {
+	c.metadataMu.Lock()
+	cached, ok := c.metadata[authorizationServerID]
+	c.metadataMu.Unlock()
+	if ok {
+		return &cached, nil
+	}
+
+	client := c.client
+
+	path := "/.well-known/openid-configuration"
+	if authorizationServerID != "" {
+		path = fmt.Sprintf("/oauth2/%s/.well-known/openid-configuration", url.PathEscape(authorizationServerID))
+	}
+
+	rq := client.CloneRequestExecutor()
+	req, err := rq.WithAccept("application/json").NewRequest("GET", path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metadata of authorization server %q: %w", authorizationServerID, err)
+	}
+
+	metadata := &AuthorizationServerMetadata{}
+	_, err = rq.Do(ctx, req, metadata)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, fmt.Errorf("authorization server %q does not exist", authorizationServerID)
+		}
+		return nil, fmt.Errorf("failed to get metadata of authorization server %q: %w", authorizationServerID, err)
+	}
+	metadata.DiscoveryURL = client.GetConfig().Okta.Client.OrgUrl + path
+
+	c.metadataMu.Lock()
+	c.metadata[authorizationServerID] = *metadata
+	c.metadataMu.Unlock()
+
+	return metadata, nil
+}
@@ -0,0 +1,52 @@
+package okta
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// ClientCache creates a Client per Okta organization and reuses it for subsequent calls, so all requests to an
+// organization share the rate limits reported by Okta and its access tokens. The client of an organization is
+// replaced, once its credentials change.
+type ClientCache struct {
+	config Config
+
+	mu      sync.Mutex
+	clients map[string]cachedClient
+}
+
+type cachedClient struct {
+	credentials Credentials
+	client      *Client
+}
+
+var _ ClientFactory = &ClientCache{}
+
+// NewClientCache returns a cache creating clients with the given config. Its org URL and credentials are ignored.
+func NewClientCache(config Config) *ClientCache {
+	return &ClientCache{config: config, clients: map[string]cachedClient{}}
+}
+
+// Client returns the client of the Okta organization with the given URL using the given credentials.
+func (c *ClientCache) Client(ctx context.Context, orgURL string, credentials Credentials) (API, error) {
+	orgURL = strings.TrimSuffix(orgURL, "/")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cached, ok := c.clients[orgURL]; ok && cached.credentials.equal(credentials) {
+		return cached.client, nil
+	}
+
+	config := c.config
+	config.OrgURL = orgURL
+	config.Credentials = credentials
+	client, err := NewClient(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	c.clients[orgURL] = cachedClient{credentials: credentials, client: client}
+	return client, nil
+}
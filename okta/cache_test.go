@@ -0,0 +1,34 @@
+package okta
+
+import (
+	"context"
+	"testing"
+)
+
+func TestClientCache(t *testing.T) {
+	cache := NewClientCache(Config{})
+	ctx := context.Background()
+
+	a, err := cache.Client(ctx, "https://a.example.com", Credentials{Token: "token"})
+	if err != nil {
+		t.Fatalf("error creating client: %v", err)
+	}
+	if a.OrgURL() != "https://a.example.com" {
+		t.Errorf("got org URL %q, wanted %q", a.OrgURL(), "https://a.example.com")
+	}
+
+	cached, err := cache.Client(ctx, "https://a.example.com/", Credentials{Token: "token"})
+	if err != nil || cached != a {
+		t.Errorf("got client %p and error %v, wanted cached client %p", cached, err, a)
+	}
+
+	b, err := cache.Client(ctx, "https://b.example.com", Credentials{Token: "token"})
+	if err != nil || b == a || b.OrgURL() != "https://b.example.com" {
+		t.Errorf("got client %p and error %v, wanted a client of another organization", b, err)
+	}
+
+	rotated, err := cache.Client(ctx, "https://a.example.com", Credentials{Token: "other"})
+	if err != nil || rotated == a {
+		t.Errorf("got client %p and error %v, wanted a new client for the new token", rotated, err)
+	}
+}
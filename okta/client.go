@@ -2,34 +2,180 @@ package okta
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/okta/okta-sdk-golang/v2/okta"
+	"github.com/okta/okta-sdk-golang/v2/okta/query"
 )
 
-var (
-	initClient sync.Once
+// Config of the Okta API client. Unset values are read from the environment (e.g. OKTA_CLIENT_ORGURL and
+// OKTA_CLIENT_TOKEN) or from an okta.yaml file, see https://github.com/okta/okta-sdk-golang#configuration-reference.
+type Config struct {
+	OrgURL string
+	Credentials
 
-	// Do not use these two directly! Use getContextAndClient instead!
-	ctx    context.Context
+	// HTTPClient used to call the Okta API. If nil, a client using http.DefaultTransport is used. Either way, requests
+	// are subject to the rate limits reported by Okta and retried on transient server errors.
+	HTTPClient *http.Client
+
+	// RequestTimeout limits the duration of a single request to the Okta API, including retries of transient server
+	// errors. It is rounded up to full seconds. Requests are only limited by their context, if zero.
+	RequestTimeout time.Duration
+}
+
+// configEnv are the environment variables configuring the Okta organization and credentials of a Client.
+var configEnv = []string{"OKTA_CLIENT_ORGURL", "OKTA_CLIENT_TOKEN", "OKTA_CLIENT_CLIENTID", "OKTA_CLIENT_PRIVATEKEY"}
+
+// IsConfigured returns true, if an Okta organization is configured by the environment or the okta.yaml file of the
+// user, see Config. Clients created without an org URL and credentials use this configuration.
+func IsConfigured() bool {
+	for _, env := range configEnv {
+		if os.Getenv(env) != "" {
+			return true
+		}
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(filepath.Join(home, ".okta", "okta.yaml"))
+	return err == nil
+}
+
+// Client implements API using the Okta management API of a single Okta organization. All methods use the given context
+// for their requests to the Okta API.
+type Client struct {
 	client *okta.Client
-)
 
-// getContextAndClient to use the Okta API.
-func getContextAndClient() (context.Context, *okta.Client) {
-	initClient.Do(func() {
-		var err error
-		ctx, client, err = okta.NewClient(
-			context.Background(),
-			okta.WithCache(false),
-		)
-		if err != nil {
-			panic(fmt.Errorf("error while initializing Okta client: %w", err))
+	// metadata caches the discovery metadata of authorization servers by their ID. It does not change, while the
+	// authorization server exists.
+	metadataMu sync.Mutex
+	metadata   map[string]AuthorizationServerMetadata
+}
+
+var _ API = &Client{}
+
+// NewClient returns a client for the Okta organization described by the given config. An error is returned, if the
+// config is incomplete or invalid.
+func NewClient(ctx context.Context, config Config) (*Client, error) {
+	setters := []okta.ConfigSetter{okta.WithCache(false)}
+	if config.OrgURL != "" {
+		setters = append(setters, okta.WithOrgUrl(config.OrgURL))
+	}
+	credentials, err := config.Credentials.setters()
+	if err != nil {
+		return nil, err
+	}
+	setters = append(setters, credentials...)
+
+	// Copy the HTTP client, so its transport can be wrapped without affecting other users of the client.
+	httpClient := &http.Client{}
+	if config.HTTPClient != nil {
+		*httpClient = *config.HTTPClient
+	}
+	httpClient.Transport = newTransport(httpClient.Transport)
+	setters = append(setters, okta.WithHttpClientPtr(httpClient))
+
+	if config.RequestTimeout > 0 {
+		seconds := int64((config.RequestTimeout + time.Second - 1) / time.Second)
+		setters = append(setters, okta.WithRequestTimeout(seconds))
+	}
+
+	_, client, err := okta.NewClient(ctx, setters...)
+	if err != nil {
+		return nil, fmt.Errorf("error while initializing Okta client: %w", err)
+	}
+
+	return &Client{client: client, metadata: map[string]AuthorizationServerMetadata{}}, nil
+}
+
+// OrgURL returns the URL of the Okta organization the client manages.
+func (c *Client) OrgURL() string {
+	return c.client.GetConfig().Okta.Client.OrgUrl
+}
+
+// Ping lists a single trusted origin, verifying that the Okta organization is reachable and accepts the credentials.
+// Trusted origins are limited separately from applications, so probing does not consume the rate limit of the
+// applications API used by reconciliations.
+func (c *Client) Ping(ctx context.Context) error {
+	_, _, err := c.client.TrustedOrigin.ListOrigins(ctx, query.NewQueryParams(query.WithLimit(1)))
+	if err != nil {
+		return fmt.Errorf("failed to reach Okta organization %q: %w", c.OrgURL(), err)
+	}
+	return nil
+}
+
+// IsUnreachable returns true, if the error indicates that the Okta organization cannot be used at all, i.e. it cannot
+// be reached (DNS, dial, TLS or timeout errors) or rejects the credentials of the operator. Rate limiting and errors of
+// single requests, like missing resources or validation errors, are not considered.
+func IsUnreachable(err error) bool {
+	var rateLimitErr *RateLimitError
+	if err == nil || errors.As(err, &rateLimitErr) {
+		return false
+	}
+	if errors.Is(err, ErrClientUnavailable) {
+		return true
+	}
+
+	var e *okta.Error
+	if errors.As(err, &e) {
+		switch e.ErrorCode {
+		case "E0000004", // Authentication failed (401)
+			"E0000011", // Invalid token provided (401)
+			"E0000006", // You do not have permission to perform the requested action (403)
+			"E0000015": // You do not have permission to access the feature you are requesting (403)
+			return true
 		}
-	})
+		switch e.ErrorMessage {
+		case "invalid_client", "invalid_grant", "unauthorized_client", "invalid_token", "insufficient_scope":
+			return true
+		}
+		return false
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	if isTLSError(err) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// isTLSError returns true, if the TLS handshake with the Okta organization failed, e.g. because its certificate is not
+// trusted.
+func isTLSError(err error) bool {
+	var verificationErr *tls.CertificateVerificationError
+	var recordHeaderErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	return errors.As(err, &verificationErr) || errors.As(err, &recordHeaderErr) || errors.As(err, &alertErr) ||
+		errors.As(err, &unknownAuthorityErr) || errors.As(err, &hostnameErr) || errors.As(err, &invalidErr)
+}
 
-	return ctx, client
+// isNotFound returns true, if the error is an Okta API error for a resource that does not exist.
+func isNotFound(err error) bool {
+	var e *okta.Error
+	return errors.As(err, &e) && strings.HasPrefix(e.ErrorSummary, "Not found")
 }
 
 // _true returns a pointer to a boolean with the value true.
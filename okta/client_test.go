@@ -0,0 +1,440 @@
+package okta
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/okta/okta-sdk-golang/v2/okta"
+
+	"github.com/jaconi-io/okta-operator/okta/oktatest"
+)
+
+// newTestClient returns a client of a fake Okta organization.
+func newTestClient(t *testing.T) (*Client, *oktatest.Server) {
+	t.Helper()
+
+	server := oktatest.NewServer("token")
+	httpServer := httptest.NewTLSServer(server)
+	t.Cleanup(httpServer.Close)
+
+	client, err := NewClient(context.Background(), Config{
+		OrgURL:      httpServer.URL,
+		Credentials: Credentials{Token: "token"},
+		HTTPClient:  httpServer.Client(),
+	})
+	if err != nil {
+		t.Fatalf("error creating client: %v", err)
+	}
+	return client, server
+}
+
+func TestApplication(t *testing.T) {
+	client, server := newTestClient(t)
+	ctx := context.Background()
+
+	settings := ApplicationSettings{
+		Label:                   "test-client",
+		RedirectUris:            []string{"https://example.com/callback"},
+		ApplicationType:         "web",
+		GrantTypes:              []string{"authorization_code"},
+		ResponseTypes:           []string{"code"},
+		TokenEndpointAuthMethod: "client_secret_basic",
+	}
+	app, err := client.CreateApplication(ctx, settings)
+	if err != nil {
+		t.Fatalf("error creating application: %v", err)
+	}
+	if app.ID == "" || app.ClientID == "" || app.ClientSecret == "" {
+		t.Errorf("got application %+v, wanted ID, client ID and client secret", app)
+	}
+
+	found, err := client.GetApplicationByLabel(ctx, "test-client")
+	if err != nil || found == nil || found.ID != app.ID {
+		t.Fatalf("got application %+v and error %v, wanted application %q", found, err, app.ID)
+	}
+	if found.ClientSecret != "" {
+		t.Errorf("got client secret %q, wanted none", found.ClientSecret)
+	}
+	if len(found.GrantTypes) != 1 || found.RedirectUris[0] != "https://example.com/callback" {
+		t.Errorf("got application %+v, wanted settings %+v", found, settings)
+	}
+
+	settings.ClientUri = "https://example.com"
+	settings.Keys = []JSONWebKey{{KeyType: "EC", KeyID: "key", Curve: "P-256", X: "x", Y: "y"}}
+	err = client.UpdateApplication(ctx, app, settings)
+	if err != nil {
+		t.Fatalf("error updating application: %v", err)
+	}
+
+	found, err = client.GetApplicationByID(ctx, app.ID)
+	if err != nil || found == nil {
+		t.Fatalf("got application %+v and error %v, wanted application %q", found, err, app.ID)
+	}
+	if found.ClientUri != "https://example.com" || found.ClientID != app.ClientID {
+		t.Errorf("got application %+v, wanted updated settings", found)
+	}
+	if len(found.Keys) != 1 || found.Keys[0].KeyID != "key" {
+		t.Errorf("got keys %+v, wanted key %q", found.Keys, "key")
+	}
+
+	assigned, err := client.IsApplicationGroupAssigned(ctx, app, "group")
+	if err != nil || assigned {
+		t.Errorf("got assigned %t and error %v, wanted no group assignment", assigned, err)
+	}
+	for i := 0; i < 2; i++ {
+		err = client.CreateApplicationGroupAssignment(ctx, app, "group")
+		if err != nil {
+			t.Errorf("error creating group assignment: %v", err)
+		}
+	}
+	assigned, err = client.IsApplicationGroupAssigned(ctx, app, "group")
+	if err != nil || !assigned {
+		t.Errorf("got assigned %t and error %v, wanted group assignment", assigned, err)
+	}
+
+	err = client.DeleteApplication(ctx, app)
+	if err != nil {
+		t.Fatalf("error deleting application: %v", err)
+	}
+
+	found, err = client.GetApplicationByID(ctx, app.ID)
+	if err != nil || found != nil {
+		t.Errorf("got application %+v and error %v, wanted none", found, err)
+	}
+	if labels := server.ApplicationLabels(); len(labels) != 0 {
+		t.Errorf("got applications %v, wanted none", labels)
+	}
+}
+
+func TestGetApplicationByLabel(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	for _, label := range []string{"test-client-2", "test-client"} {
+		_, err := client.CreateApplication(ctx, ApplicationSettings{Label: label})
+		if err != nil {
+			t.Fatalf("error creating application: %v", err)
+		}
+	}
+
+	app, err := client.GetApplicationByLabel(ctx, "test-client")
+	if err != nil || app == nil || app.Label != "test-client" {
+		t.Errorf("got application %+v and error %v, wanted application %q", app, err, "test-client")
+	}
+
+	app, err = client.GetApplicationByLabel(ctx, "unknown")
+	if err != nil || app != nil {
+		t.Errorf("got application %+v and error %v, wanted none", app, err)
+	}
+
+	_, err = client.CreateApplication(ctx, ApplicationSettings{Label: "test-client"})
+	if err != nil {
+		t.Fatalf("error creating application: %v", err)
+	}
+	_, err = client.GetApplicationByLabel(ctx, "test-client")
+	if err == nil {
+		t.Errorf("expected error getting ambiguous application")
+	}
+}
+
+func TestGetApplicationByLabelPaged(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	// The application with the exact label is beyond the first page of prefix matches.
+	for i := 0; i < 200; i++ {
+		_, err := client.CreateApplication(ctx, ApplicationSettings{Label: fmt.Sprintf("test-client-%d", i)})
+		if err != nil {
+			t.Fatalf("error creating application: %v", err)
+		}
+	}
+	created, err := client.CreateApplication(ctx, ApplicationSettings{Label: "test-client"})
+	if err != nil {
+		t.Fatalf("error creating application: %v", err)
+	}
+
+	app, err := client.GetApplicationByLabel(ctx, "test-client")
+	if err != nil || app == nil || app.ID != created.ID {
+		t.Errorf("got application %+v and error %v, wanted application %q", app, err, created.ID)
+	}
+}
+
+func TestDeactivateApplication(t *testing.T) {
+	client, server := newTestClient(t)
+	ctx := context.Background()
+
+	app, err := client.CreateApplication(ctx, ApplicationSettings{Label: "test-client"})
+	if err != nil {
+		t.Fatalf("error creating application: %v", err)
+	}
+
+	err = client.DeactivateApplication(ctx, app)
+	if err != nil {
+		t.Errorf("error deactivating application: %v", err)
+	}
+	if labels := server.ApplicationLabels(); len(labels) != 1 {
+		t.Errorf("got applications %v, wanted the deactivated one", labels)
+	}
+}
+
+func TestClientSecrets(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	app, err := client.CreateApplication(ctx, ApplicationSettings{Label: "test-client", TokenEndpointAuthMethod: "client_secret_post"})
+	if err != nil {
+		t.Fatalf("error creating application: %v", err)
+	}
+
+	secret, err := client.CreateClientSecret(ctx, app)
+	if err != nil || secret.Secret == "" || secret.Created.IsZero() {
+		t.Fatalf("got client secret %+v and error %v, wanted a new client secret", secret, err)
+	}
+
+	_, err = client.CreateClientSecret(ctx, app)
+	if err == nil {
+		t.Errorf("expected error creating a third client secret")
+	}
+
+	secrets, err := client.ListClientSecrets(ctx, app)
+	if err != nil || len(secrets) != 2 {
+		t.Fatalf("got %d client secrets and error %v, wanted %d", len(secrets), err, 2)
+	}
+	if secrets[0].Secret != "" || secrets[0].Status != "ACTIVE" {
+		t.Errorf("got client secret %+v, wanted an active client secret without the secret", secrets[0])
+	}
+
+	err = client.DeleteClientSecret(ctx, app, secrets[0].ID)
+	if err != nil {
+		t.Errorf("error deleting client secret: %v", err)
+	}
+	err = client.DeleteClientSecret(ctx, app, "unknown")
+	if err != nil {
+		t.Errorf("error deleting unknown client secret: %v", err)
+	}
+
+	newSecret, err := client.NewSecret(ctx, app.ClientID)
+	if err != nil || newSecret == "" || newSecret == secret.Secret {
+		t.Errorf("got client secret %q and error %v, wanted a new client secret", newSecret, err)
+	}
+}
+
+func TestTrustedOrigins(t *testing.T) {
+	client, server := newTestClient(t)
+	ctx := context.Background()
+
+	for _, origin := range []string{"https://a.example.com", "https://b.example.com"} {
+		err := client.CreateTrustedOrigin(ctx, origin)
+		if err != nil {
+			t.Fatalf("error creating trusted origin: %v", err)
+		}
+	}
+
+	err := client.CreateTrustedOrigin(ctx, "https://a.example.com")
+	if err == nil {
+		t.Errorf("expected error creating duplicate trusted origin")
+	}
+
+	trusted, err := client.IsTrustedOrigin(ctx, "https://b.example.com")
+	if err != nil || !trusted {
+		t.Errorf("got trusted %t and error %v, wanted trusted origin", trusted, err)
+	}
+
+	err = client.DeactivateTrustedOrigin(ctx, "https://a.example.com")
+	if err != nil {
+		t.Errorf("error deactivating trusted origin: %v", err)
+	}
+	err = client.DeleteTrustedOrigin(ctx, "https://b.example.com")
+	if err != nil {
+		t.Errorf("error deleting trusted origin: %v", err)
+	}
+
+	trusted, err = client.IsTrustedOrigin(ctx, "https://b.example.com")
+	if err != nil || trusted {
+		t.Errorf("got trusted %t and error %v, wanted no trusted origin", trusted, err)
+	}
+	if origins := server.TrustedOrigins(); len(origins) != 1 || origins[0] != "https://a.example.com" {
+		t.Errorf("got trusted origins %v, wanted the deactivated one", origins)
+	}
+}
+
+func TestGetAuthorizationServerMetadata(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	metadata, err := client.GetAuthorizationServerMetadata(ctx, "default")
+	if err != nil {
+		t.Fatalf("error getting metadata: %v", err)
+	}
+	if metadata.Issuer != client.OrgURL()+"/oauth2/default" {
+		t.Errorf("got issuer %q, wanted %q", metadata.Issuer, client.OrgURL()+"/oauth2/default")
+	}
+	if metadata.DiscoveryURL != metadata.Issuer+"/.well-known/openid-configuration" {
+		t.Errorf("got discovery URL %q, wanted the one of the issuer", metadata.DiscoveryURL)
+	}
+}
+
+func TestGetAuthorizationServerMetadataCached(t *testing.T) {
+	requests := 0
+	server := oktatest.NewServer("token")
+	httpServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		server.ServeHTTP(w, r)
+	}))
+	t.Cleanup(httpServer.Close)
+
+	ctx := context.Background()
+	client, err := NewClient(ctx, Config{
+		OrgURL:      httpServer.URL,
+		Credentials: Credentials{Token: "token"},
+		HTTPClient:  httpServer.Client(),
+	})
+	if err != nil {
+		t.Fatalf("error creating client: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		metadata, err := client.GetAuthorizationServerMetadata(ctx, "default")
+		if err != nil || metadata.Issuer != client.OrgURL()+"/oauth2/default" {
+			t.Fatalf("got metadata %v and error %v, wanted the metadata of the authorization server", metadata, err)
+		}
+		metadata.Issuer = "changed"
+	}
+	if requests != 1 {
+		t.Errorf("got %d requests, wanted %d", requests, 1)
+	}
+}
+
+func TestInvalidToken(t *testing.T) {
+	client, server := newTestClient(t)
+	ctx := context.Background()
+	server.Token = "other"
+
+	_, err := client.GetApplicationByLabel(ctx, "test-client")
+	if err == nil {
+		t.Errorf("expected error using invalid token")
+	}
+}
+
+func TestPing(t *testing.T) {
+	client, server := newTestClient(t)
+	ctx := context.Background()
+
+	err := client.Ping(ctx)
+	if err != nil {
+		t.Fatalf("error pinging Okta organization: %v", err)
+	}
+
+	_, err = client.GetApplicationByID(ctx, "unknown")
+	if IsUnreachable(err) {
+		t.Errorf("got unreachable organization for missing application: %v", err)
+	}
+
+	server.Token = "revoked"
+	err = client.Ping(ctx)
+	if err == nil || !IsUnreachable(err) {
+		t.Errorf("got error %v, wanted unreachable organization using revoked token", err)
+	}
+}
+
+func TestPingUnknownHost(t *testing.T) {
+	client, err := NewClient(context.Background(), Config{
+		OrgURL:      "https://unknown.invalid",
+		Credentials: Credentials{Token: "token"},
+	})
+	if err != nil {
+		t.Fatalf("error creating client: %v", err)
+	}
+
+	err = client.Ping(context.Background())
+	if err == nil || !IsUnreachable(err) {
+		t.Errorf("got error %v, wanted unreachable organization", err)
+	}
+}
+
+func TestIsUnreachable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"rate limit", &url.Error{Op: "Get", URL: "https://example.okta.com/api/v1/apps", Err: &RateLimitError{}}, false},
+		{"dns", &url.Error{Op: "Get", Err: &net.DNSError{Err: "no such host", IsNotFound: true}}, true},
+		{"dial", &url.Error{Op: "Get", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}, true},
+		{"read", &url.Error{Op: "Get", Err: &net.OpError{Op: "read", Err: errors.New("connection reset by peer")}}, false},
+		{"tls", &url.Error{Op: "Get", Err: &tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}}, true},
+		{"timeout", &url.Error{Op: "Get", Err: context.DeadlineExceeded}, true},
+		{"canceled", &url.Error{Op: "Get", Err: context.Canceled}, false},
+		{"invalid token", &okta.Error{ErrorCode: "E0000011"}, true},
+		{"forbidden", &okta.Error{ErrorCode: "E0000006"}, true},
+		{"not found", &okta.Error{ErrorCode: "E0000007"}, false},
+		{"invalid client", &okta.Error{ErrorMessage: "invalid_client"}, true},
+		{"unavailable", ErrClientUnavailable, true},
+		{"nil", nil, false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsUnreachable(tt.err); got != tt.want {
+				t.Errorf("got %t, wanted %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsConfigured(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	for _, env := range configEnv {
+		t.Setenv(env, "")
+	}
+	if IsConfigured() {
+		t.Errorf("got configured Okta organization, wanted none")
+	}
+
+	t.Setenv("OKTA_CLIENT_ORGURL", "https://example.okta.com")
+	if !IsConfigured() {
+		t.Errorf("got no Okta organization, wanted the one configured by the environment")
+	}
+}
+
+func TestCanceledContext(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := client.GetApplicationByLabel(ctx, "test-client")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, wanted %v", err, context.Canceled)
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	httpServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done() // The Okta API hangs.
+	}))
+	t.Cleanup(httpServer.Close)
+
+	client, err := NewClient(context.Background(), Config{
+		OrgURL:         httpServer.URL,
+		Credentials:    Credentials{Token: "token"},
+		HTTPClient:     httpServer.Client(),
+		RequestTimeout: time.Second,
+	})
+	if err != nil {
+		t.Fatalf("error creating client: %v", err)
+	}
+
+	_, err = client.GetApplicationByLabel(context.Background(), "test-client")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, wanted %v", err, context.DeadlineExceeded)
+	}
+}
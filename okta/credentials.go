@@ -0,0 +1,123 @@
+package okta
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/okta/okta-sdk-golang/v2/okta"
+)
+
+// DefaultScopes are requested for the access tokens of an Okta service application, if no scopes are configured. They
+// allow managing applications, trusted origins and group assignments.
+var DefaultScopes = []string{"okta.apps.manage", "okta.trustedOrigins.manage", "okta.groups.manage"}
+
+// Credentials authenticate the operator at an Okta organization. Either an API token or the client ID and private key
+// of an Okta service application using OAuth 2.0 client credentials with private key JWT are required. Unset values
+// are read from the environment, see Config.
+type Credentials struct {
+	// Token is an SSWS API token.
+	Token string
+
+	// ClientID of the Okta service application. If set, the operator authenticates using OAuth 2.0 instead of the
+	// API token.
+	ClientID string
+
+	// PrivateKey signing the client assertions of the service application. PEM encoded RSA (PKCS #1 or PKCS #8) and
+	// P-256 EC keys are supported.
+	PrivateKey []byte
+
+	// PrivateKeyID is the ID of the public key registered with the service application. It is optional, if the
+	// service application has a single public key.
+	PrivateKeyID string
+
+	// Scopes requested for the access tokens of the service application. Defaults to DefaultScopes.
+	Scopes []string
+}
+
+// equal returns true, if both credentials are the same.
+func (c Credentials) equal(other Credentials) bool {
+	return c.Token == other.Token &&
+		c.ClientID == other.ClientID &&
+		bytes.Equal(c.PrivateKey, other.PrivateKey) &&
+		c.PrivateKeyID == other.PrivateKeyID &&
+		slices.Equal(c.Scopes, other.Scopes)
+}
+
+// setters returns the configuration of the Okta SDK for the credentials.
+func (c Credentials) setters() ([]okta.ConfigSetter, error) {
+	if c.ClientID == "" {
+		if c.Token == "" {
+			return nil, nil
+		}
+		return []okta.ConfigSetter{okta.WithToken(c.Token)}, nil
+	}
+
+	signer, err := newKeySigner(c.PrivateKey, c.PrivateKeyID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key of Okta service application %q: %w", c.ClientID, err)
+	}
+
+	scopes := c.Scopes
+	if len(scopes) == 0 {
+		scopes = DefaultScopes
+	}
+
+	return []okta.ConfigSetter{
+		okta.WithAuthorizationMode("PrivateKey"),
+		okta.WithClientId(c.ClientID),
+		okta.WithScopes(scopes),
+		okta.WithPrivateKeySigner(signer),
+	}, nil
+}
+
+// newKeySigner returns a signer for client assertions using the PEM encoded private key. The SDK only supports PKCS #1
+// RSA keys, while Okta generates PKCS #8 keys.
+func newKeySigner(privateKeyPEM []byte, keyID string) (jose.Signer, error) {
+	block, _ := pem.Decode(privateKeyPEM)
+	if block == nil {
+		return nil, errors.New("no PEM encoded private key found")
+	}
+
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var algorithm jose.SignatureAlgorithm
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		algorithm = jose.RS256
+	case *ecdsa.PrivateKey:
+		if k.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("unsupported curve %s", k.Curve.Params().Name)
+		}
+		algorithm = jose.ES256
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+
+	options := &jose.SignerOptions{}
+	if keyID != "" {
+		options = options.WithHeader("kid", keyID)
+	}
+	return jose.NewSigner(jose.SigningKey{Algorithm: algorithm, Key: key}, options)
+}
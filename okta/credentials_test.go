@@ -0,0 +1,136 @@
+package okta
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jaconi-io/okta-operator/okta/oktatest"
+)
+
+// encodePrivateKey returns the PEM encoded private key in the given format.
+func encodePrivateKey(t *testing.T, key crypto.Signer, format string) []byte {
+	t.Helper()
+
+	var der []byte
+	var err error
+	switch format {
+	case "RSA PRIVATE KEY":
+		der = x509.MarshalPKCS1PrivateKey(key.(*rsa.PrivateKey))
+	case "EC PRIVATE KEY":
+		der, err = x509.MarshalECPrivateKey(key.(*ecdsa.PrivateKey))
+	default:
+		der, err = x509.MarshalPKCS8PrivateKey(key)
+	}
+	if err != nil {
+		t.Fatalf("error encoding private key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: format, Bytes: der})
+}
+
+func TestNewKeySigner(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error generating RSA key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("error generating EC key: %v", err)
+	}
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatalf("error generating EC key: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		pem     []byte
+		wantErr bool
+	}{
+		{name: "PKCS #1 RSA", pem: encodePrivateKey(t, rsaKey, "RSA PRIVATE KEY")},
+		{name: "PKCS #8 RSA", pem: encodePrivateKey(t, rsaKey, "PRIVATE KEY")},
+		{name: "EC", pem: encodePrivateKey(t, ecKey, "EC PRIVATE KEY")},
+		{name: "PKCS #8 EC", pem: encodePrivateKey(t, ecKey, "PRIVATE KEY")},
+		{name: "P-384 EC", pem: encodePrivateKey(t, p384Key, "EC PRIVATE KEY"), wantErr: true},
+		{name: "no PEM", pem: []byte("secret"), wantErr: true},
+		{name: "certificate", pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE"}), wantErr: true},
+	}
+	for _, tt := range tests {
+		_, err := newKeySigner(tt.pem, "kid")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: got error %v, wanted error %t", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCredentialsSetters(t *testing.T) {
+	setters, err := Credentials{}.setters()
+	if err != nil || len(setters) != 0 {
+		t.Errorf("got %d setters and error %v, wanted none", len(setters), err)
+	}
+
+	_, err = Credentials{ClientID: "client", PrivateKey: []byte("invalid")}.setters()
+	if err == nil {
+		t.Errorf("expected error using invalid private key")
+	}
+}
+
+func TestOAuthClient(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error generating RSA key: %v", err)
+	}
+
+	server := oktatest.NewServer("token")
+	server.ServiceApps["client"] = key.Public()
+	httpServer := httptest.NewTLSServer(server)
+	t.Cleanup(httpServer.Close)
+
+	client, err := NewClient(context.Background(), Config{
+		OrgURL: httpServer.URL,
+		Credentials: Credentials{
+			ClientID:   "client",
+			PrivateKey: encodePrivateKey(t, key, "PRIVATE KEY"),
+		},
+		HTTPClient: httpServer.Client(),
+	})
+	if err != nil {
+		t.Fatalf("error creating client: %v", err)
+	}
+
+	err = client.CreateTrustedOrigin(context.Background(), "https://example.com")
+	if err != nil {
+		t.Fatalf("error creating trusted origin using access token: %v", err)
+	}
+	if origins := server.TrustedOrigins(); len(origins) != 1 {
+		t.Errorf("got trusted origins %q, wanted one", origins)
+	}
+
+	// Client assertions signed with a key not registered with the service application are rejected.
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("error generating EC key: %v", err)
+	}
+	client, err = NewClient(context.Background(), Config{
+		OrgURL: httpServer.URL,
+		Credentials: Credentials{
+			ClientID:   "client",
+			PrivateKey: encodePrivateKey(t, otherKey, "EC PRIVATE KEY"),
+		},
+		HTTPClient: httpServer.Client(),
+	})
+	if err != nil {
+		t.Fatalf("error creating client: %v", err)
+	}
+	err = client.CreateTrustedOrigin(context.Background(), "https://other.example.com")
+	if err == nil {
+		t.Errorf("expected error using the wrong private key")
+	}
+}
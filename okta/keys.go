@@ -0,0 +1,86 @@
+package okta
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/okta/okta-sdk-golang/v2/okta"
+)
+
+// JSONWebKey is a public key registered with an application using private key JWT client authentication. The Okta SDK
+// only supports RSA keys, so the keys are handled separately from the rest of the application.
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid,omitempty"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+
+	// RSA keys
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// Elliptic curve keys
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+	Y     string `json:"y,omitempty"`
+}
+
+// applicationKeys is the part of the JSON representation of an application containing its public keys.
+type applicationKeys struct {
+	Settings struct {
+		OauthClient struct {
+			Jwks struct {
+				Keys []JSONWebKey `json:"keys"`
+			} `json:"jwks"`
+		} `json:"oauthClient"`
+	} `json:"settings"`
+}
+
+// parseApplication from its JSON representation, including its public keys.
+func parseApplication(body []byte) (*Application, error) {
+	var oidcApp okta.OpenIdConnectApplication
+	err := json.Unmarshal(body, &oidcApp)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys applicationKeys
+	err = json.Unmarshal(body, &keys)
+	if err != nil {
+		return nil, err
+	}
+
+	app := toApplication(&oidcApp)
+	app.Keys = keys.Settings.OauthClient.Jwks.Keys
+	return app, nil
+}
+
+// applicationBody returns the JSON representation of an application including the given public keys.
+func applicationBody(oidcApp *okta.OpenIdConnectApplication, keys []JSONWebKey) (map[string]interface{}, error) {
+	oidcApp.Settings.OauthClient.Jwks = nil
+
+	data, err := json.Marshal(oidcApp)
+	if err != nil {
+		return nil, err
+	}
+
+	var body map[string]interface{}
+	err = json.Unmarshal(data, &body)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(keys) > 0 {
+		settings, ok := body["settings"].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("application %q has no settings", oidcApp.Label)
+		}
+		oauthClient, ok := settings["oauthClient"].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("application %q has no OAuth client settings", oidcApp.Label)
+		}
+		oauthClient["jwks"] = map[string]interface{}{"keys": keys}
+	}
+
+	return body, nil
+}
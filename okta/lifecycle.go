@@ -1,9 +1,13 @@
 package okta
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"time"
+
+	"github.com/okta/okta-sdk-golang/v2/okta"
 )
 
 // clientResponse actually contains more information, but we only need the new secret.
@@ -11,8 +15,8 @@ type clientResponse struct {
 	ClientSecret string `json:"client_secret"`
 }
 
-func NewSecret(clientID string) (string, error) {
-	ctx, client := getContextAndClient()
+func (c *Client) NewSecret(ctx context.Context, clientID string) (string, error) {
+	client := c.client
 
 	url := fmt.Sprintf("/oauth2/v1/clients/%s/lifecycle/newSecret", clientID)
 	req, err := client.CloneRequestExecutor().NewRequest("POST", url, nil)
@@ -30,11 +34,80 @@ func NewSecret(clientID string) (string, error) {
 		return "", fmt.Errorf("failed to rotate secret for client ID %q; error reading response body: %w", clientID, err)
 	}
 
-	var c clientResponse
-	err = json.Unmarshal(body, &c)
+	var response clientResponse
+	err = json.Unmarshal(body, &response)
 	if err != nil {
 		return "", fmt.Errorf("failed to rotate secret for client ID %q; error parsing response body: %w", clientID, err)
 	}
 
-	return c.ClientSecret, nil
+	return response.ClientSecret, nil
+}
+
+// ClientSecret of an application. The secret itself is only available after creating the client secret.
+type ClientSecret struct {
+	ID      string
+	Secret  string
+	Status  string
+	Created time.Time
+}
+
+// ListClientSecrets of the given application.
+func (c *Client) ListClientSecrets(ctx context.Context, app *Application) ([]ClientSecret, error) {
+	client := c.client
+
+	secrets, _, err := client.Application.ListClientSecretsForApplication(ctx, app.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list client secrets for application %q: %w", app.ID, err)
+	}
+
+	var clientSecrets []ClientSecret
+	for _, secret := range secrets {
+		clientSecrets = append(clientSecrets, toClientSecret(secret))
+	}
+
+	return clientSecrets, nil
+}
+
+// CreateClientSecret creates an additional client secret for the given application. Okta supports up to two client
+// secrets per application, so applications can switch to the new secret without downtime.
+func (c *Client) CreateClientSecret(ctx context.Context, app *Application) (*ClientSecret, error) {
+	client := c.client
+
+	secret, _, err := client.Application.CreateNewClientSecretForApplication(ctx, app.ID, okta.ClientSecretMetadata{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client secret for application %q: %w", app.ID, err)
+	}
+
+	clientSecret := toClientSecret(secret)
+	return &clientSecret, nil
+}
+
+// DeleteClientSecret deactivates and deletes a client secret of the given application.
+func (c *Client) DeleteClientSecret(ctx context.Context, app *Application, secretID string) error {
+	client := c.client
+
+	_, _, err := client.Application.DeactivateClientSecretForApplication(ctx, app.ID, secretID)
+	if err != nil && !isNotFound(err) {
+		return fmt.Errorf("failed to deactivate client secret %q of application %q: %w", secretID, app.ID, err)
+	}
+
+	_, err = client.Application.DeleteClientSecretForApplication(ctx, app.ID, secretID)
+	if err != nil && !isNotFound(err) {
+		return fmt.Errorf("failed to delete client secret %q of application %q: %w", secretID, app.ID, err)
+	}
+
+	return nil
+}
+
+// toClientSecret converts an Okta client secret to a ClientSecret.
+func toClientSecret(secret *okta.ClientSecret) ClientSecret {
+	clientSecret := ClientSecret{
+		ID:     secret.Id,
+		Secret: secret.ClientSecret,
+		Status: secret.Status,
+	}
+	if secret.Created != nil {
+		clientSecret.Created = *secret.Created
+	}
+	return clientSecret
 }
@@ -0,0 +1,34 @@
+package okta
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Metrics of the requests to the Okta API. Requests are labelled with the host of the Okta organization and the
+// endpoint their rate limit applies to (e.g. /api/v1/apps), so the number of series stays small.
+var (
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "okta_api_requests_total",
+		Help: "Number of requests to the Okta API by organization, endpoint, method and status code. Requests failing without a response have the code \"error\".",
+	}, []string{"org", "endpoint", "method", "code"})
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "okta_api_request_duration_seconds",
+		Help:    "Latency of requests to the Okta API by organization, endpoint and method.",
+		Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"org", "endpoint", "method"})
+
+	rateLimitRemaining = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "okta_api_rate_limit_remaining",
+		Help: "Requests remaining until the rate limit of the Okta API endpoint resets, as reported by its last response.",
+	}, []string{"org", "endpoint"})
+
+	rateLimitExceeded = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "okta_api_rate_limit_exceeded_total",
+		Help: "Number of requests to the Okta API not sent or rejected, because the rate limit of the endpoint is exhausted.",
+	}, []string{"org", "endpoint"})
+)
+
+// Collectors returns the collectors of the Okta API metrics, so they can be registered, e.g. with the registry of the
+// controller-runtime metrics server.
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{requestsTotal, requestDuration, rateLimitRemaining, rateLimitExceeded}
+}
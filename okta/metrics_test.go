@@ -0,0 +1,34 @@
+package okta
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	client, server := newTestClient(t)
+	server.RateLimit = 2
+	ctx := context.Background()
+	orgURL, _ := url.Parse(client.OrgURL())
+	org := orgURL.Host
+
+	for i := 0; i < 3; i++ {
+		_, _ = client.GetApplicationByLabel(ctx, "test-client")
+	}
+
+	if n := testutil.ToFloat64(requestsTotal.WithLabelValues(org, "/api/v1/apps", "GET", "200")); n != 2 {
+		t.Errorf("got %v successful requests, wanted 2", n)
+	}
+	if n := testutil.ToFloat64(rateLimitRemaining.WithLabelValues(org, "/api/v1/apps")); n != 0 {
+		t.Errorf("got %v remaining requests, wanted 0", n)
+	}
+	if n := testutil.ToFloat64(rateLimitExceeded.WithLabelValues(org, "/api/v1/apps")); n != 1 {
+		t.Errorf("got %v requests exceeding the rate limit, wanted 1", n)
+	}
+	if n := testutil.CollectAndCount(requestDuration, "okta_api_request_duration_seconds"); n == 0 {
+		t.Errorf("got no request latencies, wanted some")
+	}
+}
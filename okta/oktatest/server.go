@@ -0,0 +1,896 @@
+// Package oktatest provides a fake of the parts of the Okta management API used by the operator. It keeps the state of
+// a single Okta organization in memory and can be used with net/http/httptest or served standalone.
+package oktatest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+)
+
+// Server is a fake Okta organization. It implements the applications, application group assignments, client secrets,
+// trusted origins and OpenID Connect discovery endpoints of the Okta API, as well as the token endpoint of OAuth 2.0
+// service applications. Server is safe for concurrent use.
+type Server struct {
+	// Token is the API token requests must be authorized with ("Authorization: SSWS <token>"). If empty, any token is
+	// accepted.
+	Token string
+
+	// RateLimit is the number of requests per minute, before requests are rejected with "429 Too Many Requests". The
+	// rate limit headers are only sent, if RateLimit is greater than zero.
+	RateLimit int
+
+	// ServiceApps maps client IDs of OAuth 2.0 service applications to their public keys. Service applications request
+	// access tokens at /oauth2/v1/token using private key JWT and authorize requests with "Authorization: Bearer
+	// <access token>".
+	ServiceApps map[string]crypto.PublicKey
+
+	mu             sync.Mutex
+	accessTokens   map[string]bool
+	lastID         int
+	apps           map[string]*application
+	trustedOrigins map[string]*trustedOrigin
+	requests       int
+	rateLimitReset time.Time
+}
+
+type application struct {
+	// body is the JSON representation of the application without its client secret.
+	body    map[string]interface{}
+	groups  map[string]*groupAssignment
+	secrets []*clientSecret
+}
+
+type groupAssignment struct {
+	ID          string    `json:"id"`
+	Priority    int       `json:"priority"`
+	LastUpdated time.Time `json:"lastUpdated"`
+}
+
+type clientSecret struct {
+	ID           string    `json:"id"`
+	Status       string    `json:"status"`
+	ClientSecret string    `json:"client_secret,omitempty"`
+	SecretHash   string    `json:"secret_hash"`
+	Created      time.Time `json:"created"`
+	LastUpdated  time.Time `json:"lastUpdated"`
+}
+
+type trustedOrigin struct {
+	ID          string        `json:"id"`
+	Name        string        `json:"name"`
+	Origin      string        `json:"origin"`
+	Scopes      []interface{} `json:"scopes"`
+	Status      string        `json:"status"`
+	Created     time.Time     `json:"created"`
+	LastUpdated time.Time     `json:"lastUpdated"`
+}
+
+// apiError is the error body returned by the Okta API.
+type apiError struct {
+	ErrorCode    string       `json:"errorCode"`
+	ErrorSummary string       `json:"errorSummary"`
+	ErrorLink    string       `json:"errorLink"`
+	ErrorID      string       `json:"errorId"`
+	ErrorCauses  []errorCause `json:"errorCauses"`
+}
+
+type errorCause struct {
+	ErrorSummary string `json:"errorSummary"`
+}
+
+// maxClientSecrets is the maximum number of client secrets of an application.
+const maxClientSecrets = 2
+
+// NewServer returns a fake Okta organization without any applications or trusted origins.
+func NewServer(token string) *Server {
+	return &Server{
+		Token:          token,
+		apps:           map[string]*application{},
+		trustedOrigins: map[string]*trustedOrigin{},
+		ServiceApps:    map[string]crypto.PublicKey{},
+		accessTokens:   map[string]bool{},
+	}
+}
+
+// Reset removes all applications and trusted origins.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.apps = map[string]*application{}
+	s.trustedOrigins = map[string]*trustedOrigin{}
+	s.requests = 0
+}
+
+// ApplicationLabels returns the labels of all applications, active or not.
+func (s *Server) ApplicationLabels() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	labels := []string{}
+	for _, id := range s.appIDs() {
+		labels = append(labels, stringField(s.apps[id].body, "label"))
+	}
+	return labels
+}
+
+// TrustedOrigins returns the origins of all trusted origins, active or not.
+func (s *Server) TrustedOrigins() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	origins := []string{}
+	for _, id := range s.trustedOriginIDs() {
+		origins = append(origins, s.trustedOrigins[id].Origin)
+	}
+	return origins
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w.Header().Set("X-Okta-Request-Id", s.newID("req"))
+
+	path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(path) >= 2 && path[len(path)-2] == ".well-known" && path[len(path)-1] == "openid-configuration" {
+		s.handleOpenIDConfiguration(w, r, path[:len(path)-2])
+		return
+	}
+	if match(path, "oauth2", "v1", "token") {
+		s.handleToken(w, r)
+		return
+	}
+
+	if !s.authorized(r) {
+		s.error(w, http.StatusUnauthorized, "E0000011", "Invalid token provided")
+		return
+	}
+
+	if !s.allowRequest(w) {
+		s.error(w, http.StatusTooManyRequests, "E0000047", "API call exceeded rate limit due to too many requests.")
+		return
+	}
+
+	switch {
+	case match(path, "api", "v1", "apps"):
+		s.handleApps(w, r)
+	case match(path, "api", "v1", "apps", "*"):
+		s.handleApp(w, r, path[3])
+	case match(path, "api", "v1", "apps", "*", "lifecycle", "*"):
+		s.handleAppLifecycle(w, r, path[3], path[5])
+	case match(path, "api", "v1", "apps", "*", "groups", "*"):
+		s.handleGroupAssignment(w, r, path[3], path[5])
+	case match(path, "api", "v1", "apps", "*", "credentials", "secrets"):
+		s.handleClientSecrets(w, r, path[3])
+	case match(path, "api", "v1", "apps", "*", "credentials", "secrets", "*"):
+		s.handleClientSecret(w, r, path[3], path[6])
+	case match(path, "api", "v1", "apps", "*", "credentials", "secrets", "*", "lifecycle", "*"):
+		s.handleClientSecretLifecycle(w, r, path[3], path[6], path[8])
+	case match(path, "oauth2", "v1", "clients", "*", "lifecycle", "newSecret"):
+		s.handleNewSecret(w, r, path[3])
+	case match(path, "api", "v1", "trustedOrigins"):
+		s.handleTrustedOrigins(w, r)
+	case match(path, "api", "v1", "trustedOrigins", "*"):
+		s.handleTrustedOrigin(w, r, path[3])
+	case match(path, "api", "v1", "trustedOrigins", "*", "lifecycle", "*"):
+		s.handleTrustedOriginLifecycle(w, r, path[3], path[5])
+	default:
+		s.notFound(w, r.URL.Path, "Resource")
+	}
+}
+
+// match returns true, if the path segments match the pattern. The pattern segment "*" matches any segment.
+func match(path []string, pattern ...string) bool {
+	if len(path) != len(pattern) {
+		return false
+	}
+	for i := range pattern {
+		if pattern[i] != "*" && pattern[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// authorized returns true, if the request carries the API token of the server or an access token issued to a service
+// application.
+func (s *Server) authorized(r *http.Request) bool {
+	if accessToken, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return s.accessTokens[accessToken]
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "SSWS ")
+	if !ok || token == "" {
+		return false
+	}
+	return s.Token == "" || token == s.Token
+}
+
+// allowRequest counts the request against the rate limit and sets the rate limit headers. It returns false, if the
+// rate limit is exceeded.
+func (s *Server) allowRequest(w http.ResponseWriter) bool {
+	if s.RateLimit <= 0 {
+		return true
+	}
+
+	now := time.Now()
+	if !now.Before(s.rateLimitReset) {
+		s.requests = 0
+		s.rateLimitReset = now.Truncate(time.Minute).Add(time.Minute)
+	}
+	s.requests++
+
+	remaining := s.RateLimit - s.requests
+	if remaining < 0 {
+		remaining = 0
+	}
+	w.Header().Set("X-Rate-Limit-Limit", strconv.Itoa(s.RateLimit))
+	w.Header().Set("X-Rate-Limit-Remaining", strconv.Itoa(remaining))
+	w.Header().Set("X-Rate-Limit-Reset", strconv.FormatInt(s.rateLimitReset.Unix(), 10))
+
+	return s.requests <= s.RateLimit
+}
+
+// handleApps handles /api/v1/apps.
+func (s *Server) handleApps(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		q := strings.ToLower(r.URL.Query().Get("q"))
+		after := r.URL.Query().Get("after")
+		limit := limit(r)
+
+		// Like Okta, pages are linked by the ID of their last application, which is passed as after cursor.
+		apps := []interface{}{}
+		for _, id := range s.appIDs() {
+			if after != "" && id <= after {
+				continue
+			}
+			body := s.apps[id].body
+			if !strings.HasPrefix(strings.ToLower(stringField(body, "label")), q) && !strings.HasPrefix(strings.ToLower(stringField(body, "name")), q) {
+				continue
+			}
+			if len(apps) == limit {
+				next := *r.URL
+				query := next.Query()
+				query.Set("after", stringField(apps[len(apps)-1].(map[string]interface{}), "id"))
+				next.RawQuery = query.Encode()
+				w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="next"`, next.String()))
+				break
+			}
+			apps = append(apps, body)
+		}
+		s.json(w, http.StatusOK, apps)
+
+	case http.MethodPost:
+		body, ok := s.decode(w, r)
+		if !ok {
+			return
+		}
+		if !s.validateApplication(w, body) {
+			return
+		}
+
+		now := time.Now().UTC()
+		id := s.newID("0oa")
+		body["id"] = id
+		body["status"] = "ACTIVE"
+		body["created"] = now
+		body["lastUpdated"] = now
+		oauthClient := object(object(body, "credentials"), "oauthClient")
+		oauthClient["client_id"] = id
+		if _, ok := oauthClient["token_endpoint_auth_method"]; !ok {
+			oauthClient["token_endpoint_auth_method"] = "client_secret_basic"
+		}
+
+		app := &application{body: body, groups: map[string]*groupAssignment{}}
+		s.apps[id] = app
+
+		// Okta only returns the client secret, when it is created.
+		response := copyJSON(body)
+		if usesClientSecret(body) {
+			secret := s.newClientSecret()
+			app.secrets = append(app.secrets, secret)
+			object(object(response, "credentials"), "oauthClient")["client_secret"] = secret.ClientSecret
+		}
+		s.json(w, http.StatusOK, response)
+
+	default:
+		s.methodNotAllowed(w)
+	}
+}
+
+// handleApp handles /api/v1/apps/{id}.
+func (s *Server) handleApp(w http.ResponseWriter, r *http.Request, id string) {
+	app, ok := s.apps[id]
+	if !ok {
+		s.notFound(w, id, "AppInstance")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.json(w, http.StatusOK, app.body)
+
+	case http.MethodPut:
+		body, ok := s.decode(w, r)
+		if !ok {
+			return
+		}
+		if !s.validateApplication(w, body) {
+			return
+		}
+
+		// The ID, status and client ID of an application cannot be changed.
+		body["id"] = id
+		body["status"] = app.body["status"]
+		body["created"] = app.body["created"]
+		body["lastUpdated"] = time.Now().UTC()
+		oauthClient := object(object(body, "credentials"), "oauthClient")
+		oauthClient["client_id"] = object(object(app.body, "credentials"), "oauthClient")["client_id"]
+		delete(oauthClient, "client_secret")
+
+		app.body = body
+		s.json(w, http.StatusOK, app.body)
+
+	case http.MethodDelete:
+		if app.body["status"] != "INACTIVE" {
+			s.error(w, http.StatusForbidden, "E0000056", "Delete application forbidden.",
+				fmt.Sprintf("The application %s must be deactivated before it can be deleted.", id))
+			return
+		}
+		delete(s.apps, id)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		s.methodNotAllowed(w)
+	}
+}
+
+// handleAppLifecycle handles /api/v1/apps/{id}/lifecycle/{operation}.
+func (s *Server) handleAppLifecycle(w http.ResponseWriter, r *http.Request, id, operation string) {
+	app, ok := s.apps[id]
+	if !ok {
+		s.notFound(w, id, "AppInstance")
+		return
+	}
+	if r.Method != http.MethodPost {
+		s.methodNotAllowed(w)
+		return
+	}
+
+	switch operation {
+	case "activate":
+		app.body["status"] = "ACTIVE"
+	case "deactivate":
+		app.body["status"] = "INACTIVE"
+	default:
+		s.notFound(w, r.URL.Path, "Resource")
+		return
+	}
+	app.body["lastUpdated"] = time.Now().UTC()
+	s.json(w, http.StatusOK, map[string]interface{}{})
+}
+
+// handleGroupAssignment handles /api/v1/apps/{id}/groups/{groupID}.
+func (s *Server) handleGroupAssignment(w http.ResponseWriter, r *http.Request, id, groupID string) {
+	app, ok := s.apps[id]
+	if !ok {
+		s.notFound(w, id, "AppInstance")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		assignment, ok := app.groups[groupID]
+		if !ok {
+			s.notFound(w, groupID, "ApplicationGroupAssignment")
+			return
+		}
+		s.json(w, http.StatusOK, assignment)
+
+	case http.MethodPut:
+		assignment := &groupAssignment{ID: groupID, Priority: len(app.groups), LastUpdated: time.Now().UTC()}
+		if existing, ok := app.groups[groupID]; ok {
+			assignment.Priority = existing.Priority
+		}
+		app.groups[groupID] = assignment
+		s.json(w, http.StatusOK, assignment)
+
+	case http.MethodDelete:
+		delete(app.groups, groupID)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		s.methodNotAllowed(w)
+	}
+}
+
+// handleClientSecrets handles /api/v1/apps/{id}/credentials/secrets.
+func (s *Server) handleClientSecrets(w http.ResponseWriter, r *http.Request, id string) {
+	app, ok := s.apps[id]
+	if !ok {
+		s.notFound(w, id, "AppInstance")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		secrets := []clientSecret{}
+		for _, secret := range app.secrets {
+			secrets = append(secrets, secret.withoutSecret())
+		}
+		s.json(w, http.StatusOK, secrets)
+
+	case http.MethodPost:
+		if len(app.secrets) >= maxClientSecrets {
+			s.error(w, http.StatusBadRequest, "E0000001", "Api validation failed: client_secret",
+				fmt.Sprintf("client_secret: You can't create more than %d client secrets for an application.", maxClientSecrets))
+			return
+		}
+		secret := s.newClientSecret()
+		app.secrets = append(app.secrets, secret)
+		s.json(w, http.StatusOK, secret)
+
+	default:
+		s.methodNotAllowed(w)
+	}
+}
+
+// handleClientSecret handles /api/v1/apps/{id}/credentials/secrets/{secretID}.
+func (s *Server) handleClientSecret(w http.ResponseWriter, r *http.Request, id, secretID string) {
+	app, secret, i := s.findClientSecret(w, id, secretID)
+	if secret == nil {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.json(w, http.StatusOK, secret.withoutSecret())
+
+	case http.MethodDelete:
+		if secret.Status != "INACTIVE" {
+			s.error(w, http.StatusBadRequest, "E0000001", "Api validation failed: client_secret",
+				"client_secret: You can't delete an active client secret.")
+			return
+		}
+		app.secrets = append(app.secrets[:i], app.secrets[i+1:]...)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		s.methodNotAllowed(w)
+	}
+}
+
+// handleClientSecretLifecycle handles /api/v1/apps/{id}/credentials/secrets/{secretID}/lifecycle/{operation}.
+func (s *Server) handleClientSecretLifecycle(w http.ResponseWriter, r *http.Request, id, secretID, operation string) {
+	app, secret, _ := s.findClientSecret(w, id, secretID)
+	if secret == nil {
+		return
+	}
+	if r.Method != http.MethodPost {
+		s.methodNotAllowed(w)
+		return
+	}
+
+	switch operation {
+	case "activate":
+		secret.Status = "ACTIVE"
+	case "deactivate":
+		active := 0
+		for _, other := range app.secrets {
+			if other.Status == "ACTIVE" {
+				active++
+			}
+		}
+		if secret.Status == "ACTIVE" && active == 1 {
+			s.error(w, http.StatusBadRequest, "E0000001", "Api validation failed: client_secret",
+				"client_secret: You can't deactivate the only active client secret of an application.")
+			return
+		}
+		secret.Status = "INACTIVE"
+	default:
+		s.notFound(w, r.URL.Path, "Resource")
+		return
+	}
+	secret.LastUpdated = time.Now().UTC()
+	s.json(w, http.StatusOK, secret.withoutSecret())
+}
+
+// findClientSecret returns the application, the client secret and its index. If either does not exist, an error is
+// written and the client secret is nil.
+func (s *Server) findClientSecret(w http.ResponseWriter, id, secretID string) (*application, *clientSecret, int) {
+	app, ok := s.apps[id]
+	if !ok {
+		s.notFound(w, id, "AppInstance")
+		return nil, nil, -1
+	}
+	for i, secret := range app.secrets {
+		if secret.ID == secretID {
+			return app, secret, i
+		}
+	}
+	s.notFound(w, secretID, "ClientSecret")
+	return nil, nil, -1
+}
+
+// handleNewSecret handles /oauth2/v1/clients/{clientID}/lifecycle/newSecret. It replaces all client secrets of the
+// application with a new one.
+func (s *Server) handleNewSecret(w http.ResponseWriter, r *http.Request, clientID string) {
+	if r.Method != http.MethodPost {
+		s.methodNotAllowed(w)
+		return
+	}
+
+	for _, app := range s.apps {
+		if object(object(app.body, "credentials"), "oauthClient")["client_id"] != clientID {
+			continue
+		}
+		secret := s.newClientSecret()
+		app.secrets = []*clientSecret{secret}
+		s.json(w, http.StatusOK, map[string]interface{}{
+			"client_id":                  clientID,
+			"client_name":                stringField(app.body, "label"),
+			"client_secret":              secret.ClientSecret,
+			"token_endpoint_auth_method": object(object(app.body, "credentials"), "oauthClient")["token_endpoint_auth_method"],
+		})
+		return
+	}
+	s.notFound(w, clientID, "OAuth2Client")
+}
+
+// handleTrustedOrigins handles /api/v1/trustedOrigins.
+func (s *Server) handleTrustedOrigins(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		var origin *string
+		if filter := r.URL.Query().Get("filter"); filter != "" {
+			value, ok := strings.CutPrefix(filter, "origin eq ")
+			unquoted, err := strconv.Unquote(value)
+			if !ok || err != nil {
+				s.error(w, http.StatusBadRequest, "E0000031", "Invalid search criteria.")
+				return
+			}
+			origin = &unquoted
+		}
+		limit := limit(r)
+
+		trustedOrigins := []*trustedOrigin{}
+		for _, id := range s.trustedOriginIDs() {
+			if origin != nil && s.trustedOrigins[id].Origin != *origin {
+				continue
+			}
+			if len(trustedOrigins) == limit {
+				break
+			}
+			trustedOrigins = append(trustedOrigins, s.trustedOrigins[id])
+		}
+		s.json(w, http.StatusOK, trustedOrigins)
+
+	case http.MethodPost:
+		var origin trustedOrigin
+		err := json.NewDecoder(r.Body).Decode(&origin)
+		if err != nil {
+			s.error(w, http.StatusBadRequest, "E0000003", "The request body was not well-formed.")
+			return
+		}
+		if origin.Name == "" || origin.Origin == "" {
+			s.error(w, http.StatusBadRequest, "E0000001", "Api validation failed: trustedOrigin",
+				"name: The field cannot be left blank", "origin: The field cannot be left blank")
+			return
+		}
+		for _, other := range s.trustedOrigins {
+			if other.Origin == origin.Origin || other.Name == origin.Name {
+				s.error(w, http.StatusBadRequest, "E0000001", "Api validation failed: trustedOrigin",
+					"origin: An object with this field already exists in the current organization")
+				return
+			}
+		}
+
+		now := time.Now().UTC()
+		origin.ID = s.newID("tos")
+		origin.Status = "ACTIVE"
+		origin.Created = now
+		origin.LastUpdated = now
+		s.trustedOrigins[origin.ID] = &origin
+		s.json(w, http.StatusOK, origin)
+
+	default:
+		s.methodNotAllowed(w)
+	}
+}
+
+// handleTrustedOrigin handles /api/v1/trustedOrigins/{id}.
+func (s *Server) handleTrustedOrigin(w http.ResponseWriter, r *http.Request, id string) {
+	origin, ok := s.trustedOrigins[id]
+	if !ok {
+		s.notFound(w, id, "TrustedOrigin")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.json(w, http.StatusOK, origin)
+
+	case http.MethodDelete:
+		delete(s.trustedOrigins, id)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		s.methodNotAllowed(w)
+	}
+}
+
+// handleTrustedOriginLifecycle handles /api/v1/trustedOrigins/{id}/lifecycle/{operation}.
+func (s *Server) handleTrustedOriginLifecycle(w http.ResponseWriter, r *http.Request, id, operation string) {
+	origin, ok := s.trustedOrigins[id]
+	if !ok {
+		s.notFound(w, id, "TrustedOrigin")
+		return
+	}
+	if r.Method != http.MethodPost {
+		s.methodNotAllowed(w)
+		return
+	}
+
+	switch operation {
+	case "activate":
+		origin.Status = "ACTIVE"
+	case "deactivate":
+		origin.Status = "INACTIVE"
+	default:
+		s.notFound(w, r.URL.Path, "Resource")
+		return
+	}
+	origin.LastUpdated = time.Now().UTC()
+	s.json(w, http.StatusOK, origin)
+}
+
+// handleOpenIDConfiguration handles the discovery documents of the org authorization server (/.well-known/...) and of
+// custom authorization servers (/oauth2/{id}/.well-known/...).
+func (s *Server) handleOpenIDConfiguration(w http.ResponseWriter, r *http.Request, path []string) {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	orgURL := fmt.Sprintf("%s://%s", scheme, r.Host)
+
+	var issuer, endpoints string
+	switch {
+	case len(path) == 0:
+		issuer, endpoints = orgURL, orgURL+"/oauth2/v1"
+	case match(path, "oauth2", "*"):
+		issuer = fmt.Sprintf("%s/oauth2/%s", orgURL, path[1])
+		endpoints = issuer + "/v1"
+	default:
+		s.notFound(w, r.URL.Path, "Resource")
+		return
+	}
+
+	s.json(w, http.StatusOK, map[string]interface{}{
+		"issuer":                 issuer,
+		"authorization_endpoint": endpoints + "/authorize",
+		"token_endpoint":         endpoints + "/token",
+		"userinfo_endpoint":      endpoints + "/userinfo",
+		"jwks_uri":               endpoints + "/keys",
+		"end_session_endpoint":   endpoints + "/logout",
+	})
+}
+
+// handleToken handles /oauth2/v1/token. It issues access tokens to service applications using the client credentials
+// grant with private key JWT client authentication. Requested scopes are not checked.
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.methodNotAllowed(w)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		s.oauthError(w, http.StatusBadRequest, "invalid_request", "The request is malformed.")
+		return
+	}
+	if grantType := r.Form.Get("grant_type"); grantType != "client_credentials" {
+		s.oauthError(w, http.StatusBadRequest, "unsupported_grant_type", fmt.Sprintf("The grant type %q is not supported.", grantType))
+		return
+	}
+
+	assertion, err := jwt.ParseSigned(r.Form.Get("client_assertion"))
+	if err != nil {
+		s.oauthError(w, http.StatusUnauthorized, "invalid_client", "The client_assertion is not a valid JWT.")
+		return
+	}
+	var claims jwt.Claims
+	if err := assertion.UnsafeClaimsWithoutVerification(&claims); err != nil {
+		s.oauthError(w, http.StatusUnauthorized, "invalid_client", "The client_assertion is not a valid JWT.")
+		return
+	}
+	publicKey, ok := s.ServiceApps[claims.Subject]
+	if !ok {
+		s.oauthError(w, http.StatusUnauthorized, "invalid_client", "The client_id is invalid.")
+		return
+	}
+	err = assertion.Claims(publicKey, &claims)
+	if err == nil {
+		err = claims.Validate(jwt.Expected{Issuer: claims.Subject, Time: time.Now()})
+	}
+	if err != nil || len(claims.Audience) != 1 || !strings.HasSuffix(claims.Audience[0], "/oauth2/v1/token") {
+		s.oauthError(w, http.StatusUnauthorized, "invalid_client", "The client_assertion is invalid.")
+		return
+	}
+
+	value := make([]byte, 30)
+	_, _ = rand.Read(value)
+	accessToken := base64.RawURLEncoding.EncodeToString(value)
+	s.accessTokens[accessToken] = true
+
+	s.json(w, http.StatusOK, map[string]interface{}{
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"access_token": accessToken,
+		"scope":        r.Form.Get("scope"),
+	})
+}
+
+// validateApplication writes an error and returns false, if the application is not a valid OpenID Connect application.
+func (s *Server) validateApplication(w http.ResponseWriter, body map[string]interface{}) bool {
+	var causes []string
+	if stringField(body, "label") == "" {
+		causes = append(causes, "label: The field cannot be left blank")
+	}
+	if signOnMode := stringField(body, "signOnMode"); signOnMode != "OPENID_CONNECT" {
+		causes = append(causes, fmt.Sprintf("signOnMode: Unsupported sign on mode %q", signOnMode))
+	}
+	if len(causes) > 0 {
+		s.error(w, http.StatusBadRequest, "E0000001", "Api validation failed: app", causes...)
+		return false
+	}
+	return true
+}
+
+// decode the JSON request body. If it is not well-formed, an error is written and false is returned.
+func (s *Server) decode(w http.ResponseWriter, r *http.Request) (map[string]interface{}, bool) {
+	var body map[string]interface{}
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil || body == nil {
+		s.error(w, http.StatusBadRequest, "E0000003", "The request body was not well-formed.")
+		return nil, false
+	}
+	return body, true
+}
+
+func (s *Server) json(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func (s *Server) error(w http.ResponseWriter, status int, code, summary string, causes ...string) {
+	body := apiError{
+		ErrorCode:    code,
+		ErrorSummary: summary,
+		ErrorLink:    code,
+		ErrorID:      w.Header().Get("X-Okta-Request-Id"),
+		ErrorCauses:  []errorCause{},
+	}
+	for _, cause := range causes {
+		body.ErrorCauses = append(body.ErrorCauses, errorCause{ErrorSummary: cause})
+	}
+	s.json(w, status, body)
+}
+
+// oauthError writes an error of the OAuth 2.0 endpoints. Unlike errors of the management API, they follow RFC 6749.
+func (s *Server) oauthError(w http.ResponseWriter, status int, code, description string) {
+	s.json(w, status, map[string]string{"error": code, "error_description": description})
+}
+
+func (s *Server) notFound(w http.ResponseWriter, resource, kind string) {
+	s.error(w, http.StatusNotFound, "E0000007", fmt.Sprintf("Not found: Resource not found: %s (%s)", resource, kind))
+}
+
+func (s *Server) methodNotAllowed(w http.ResponseWriter) {
+	s.error(w, http.StatusMethodNotAllowed, "E0000022", "The endpoint does not support the provided HTTP method")
+}
+
+// newID returns a new, unique ID with the given prefix. IDs are increasing, so sorting them sorts by creation.
+func (s *Server) newID(prefix string) string {
+	s.lastID++
+	return fmt.Sprintf("%s%017d", prefix, s.lastID)
+}
+
+func (s *Server) newClientSecret() *clientSecret {
+	value := make([]byte, 30)
+	_, _ = rand.Read(value)
+	secret := base64.RawURLEncoding.EncodeToString(value)
+	hash := sha256.Sum256([]byte(secret))
+	now := time.Now().UTC()
+
+	return &clientSecret{
+		ID:           s.newID("ocs"),
+		Status:       "ACTIVE",
+		ClientSecret: secret,
+		SecretHash:   hex.EncodeToString(hash[:8]),
+		Created:      now,
+		LastUpdated:  now,
+	}
+}
+
+// withoutSecret returns a copy of the client secret without the secret itself, as Okta only returns it on creation.
+func (c *clientSecret) withoutSecret() clientSecret {
+	secret := *c
+	secret.ClientSecret = ""
+	return secret
+}
+
+// appIDs returns the IDs of all applications ordered by creation.
+func (s *Server) appIDs() []string {
+	var ids []string
+	for id := range s.apps {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// trustedOriginIDs returns the IDs of all trusted origins ordered by creation.
+func (s *Server) trustedOriginIDs() []string {
+	var ids []string
+	for id := range s.trustedOrigins {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// limit returns the limit query parameter of the request. Okta defaults to 20 results.
+func limit(r *http.Request) int {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		return 20
+	}
+	return limit
+}
+
+// usesClientSecret returns true, if the application authenticates with a client secret.
+func usesClientSecret(body map[string]interface{}) bool {
+	switch object(object(body, "credentials"), "oauthClient")["token_endpoint_auth_method"] {
+	case "client_secret_basic", "client_secret_post", "client_secret_jwt":
+		return true
+	default:
+		return false
+	}
+}
+
+// object returns the JSON object stored under the key, creating it if necessary.
+func object(body map[string]interface{}, key string) map[string]interface{} {
+	value, ok := body[key].(map[string]interface{})
+	if !ok {
+		value = map[string]interface{}{}
+		body[key] = value
+	}
+	return value
+}
+
+func stringField(body map[string]interface{}, key string) string {
+	value, _ := body[key].(string)
+	return value
+}
+
+func copyJSON(body map[string]interface{}) map[string]interface{} {
+	data, _ := json.Marshal(body)
+	var result map[string]interface{}
+	_ = json.Unmarshal(data, &result)
+	return result
+}
@@ -0,0 +1,62 @@
+package oktatest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServeHTTPNotFound(t *testing.T) {
+	server := NewServer("token")
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/apps/unknown", nil)
+	req.Header.Set("Authorization", "SSWS token")
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("got status %d, wanted %d", rec.Code, http.StatusNotFound)
+	}
+	var body apiError
+	err := json.NewDecoder(rec.Body).Decode(&body)
+	if err != nil {
+		t.Fatalf("error decoding error body: %v", err)
+	}
+	if body.ErrorCode != "E0000007" || !strings.HasPrefix(body.ErrorSummary, "Not found") || body.ErrorID == "" {
+		t.Errorf("got error %+v, wanted a not found error", body)
+	}
+}
+
+func TestServeHTTPRateLimit(t *testing.T) {
+	server := NewServer("")
+	server.RateLimit = 2
+
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/trustedOrigins", nil)
+		req.Header.Set("Authorization", "SSWS any")
+
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+
+		if rec.Code != want {
+			t.Errorf("got status %d for request %d, wanted %d", rec.Code, i, want)
+		}
+		if rec.Header().Get("X-Rate-Limit-Limit") != "2" || rec.Header().Get("X-Rate-Limit-Reset") == "" {
+			t.Errorf("got headers %v, wanted rate limit headers", rec.Header())
+		}
+	}
+}
+
+func TestServeHTTPUnauthorized(t *testing.T) {
+	server := NewServer("token")
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/apps", nil)
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("got status %d, wanted %d", rec.Code, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,241 @@
+package okta
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxRetries   = 3
+	defaultRetryBackoff = 500 * time.Millisecond
+
+	// defaultRateLimitReset is assumed, if Okta rejects a request without reporting when its rate limit resets.
+	defaultRateLimitReset = time.Minute
+)
+
+// RateLimitError is returned, if the rate limit of an Okta API endpoint is exhausted. Requests to the endpoint are not
+// sent until the rate limit resets.
+type RateLimitError struct {
+	Endpoint string
+	Reset    time.Time
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limit of Okta API endpoint %q exceeded until %s", e.Endpoint, e.Reset.Format(time.RFC3339))
+}
+
+// RateLimitRetryAfter returns the time until the rate limit causing the error resets. It returns false, if the error is
+// not caused by an exhausted rate limit.
+func RateLimitRetryAfter(err error) (time.Duration, bool) {
+	var rateLimitErr *RateLimitError
+	if !errors.As(err, &rateLimitErr) {
+		return 0, false
+	}
+
+	retryAfter := time.Until(rateLimitErr.Reset)
+	if retryAfter < time.Second {
+		retryAfter = time.Second
+	}
+	return retryAfter, true
+}
+
+// rateLimit of an Okta API endpoint as reported by the X-Rate-Limit-* headers of its last response.
+type rateLimit struct {
+	remaining int
+	reset     time.Time
+}
+
+// rateLimitKey identifies an Okta API endpoint of an Okta organization by its host.
+type rateLimitKey struct {
+	org      string
+	endpoint string
+}
+
+// rateLimitStore records the rate limits of the Okta API endpoints per Okta organization.
+type rateLimitStore struct {
+	mu         sync.Mutex
+	rateLimits map[rateLimitKey]rateLimit
+}
+
+// sharedRateLimits are used by the transports of all clients, so the rate limits of an Okta organization are not
+// forgotten, once its Client is replaced, e.g. by a ReloadingClient or a ClientCache.
+var sharedRateLimits = newRateLimitStore()
+
+func newRateLimitStore() *rateLimitStore {
+	return &rateLimitStore{rateLimits: map[rateLimitKey]rateLimit{}}
+}
+
+// transport tracks the rate limits of the Okta API endpoints and fails requests to endpoints with an exhausted rate
+// limit with a RateLimitError. Idempotent requests failing with a transient server error are retried with an
+// exponential backoff.
+type transport struct {
+	next         http.RoundTripper
+	maxRetries   int
+	retryBackoff time.Duration
+	rateLimits   *rateLimitStore
+}
+
+func newTransport(next http.RoundTripper) *transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{
+		next:         next,
+		maxRetries:   defaultMaxRetries,
+		retryBackoff: defaultRetryBackoff,
+		rateLimits:   sharedRateLimits,
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := rateLimitEndpoint(req)
+	org := req.URL.Host
+	if reset, exhausted := t.rateLimits.exhausted(org, endpoint); exhausted {
+		rateLimitExceeded.WithLabelValues(org, endpoint).Inc()
+		return nil, &RateLimitError{Endpoint: endpoint, Reset: reset}
+	}
+
+	// Keep the body, so the request can be retried.
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		attemptReq := req.Clone(req.Context())
+		if body != nil {
+			attemptReq.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		start := time.Now()
+		resp, err := t.next.RoundTrip(attemptReq)
+		requestDuration.WithLabelValues(org, endpoint, req.Method).Observe(time.Since(start).Seconds())
+		if err != nil {
+			requestsTotal.WithLabelValues(org, endpoint, req.Method, "error").Inc()
+			return nil, err
+		}
+		requestsTotal.WithLabelValues(org, endpoint, req.Method, strconv.Itoa(resp.StatusCode)).Inc()
+
+		reset := t.rateLimits.update(org, endpoint, resp)
+		if resp.StatusCode == http.StatusTooManyRequests {
+			rateLimitExceeded.WithLabelValues(org, endpoint).Inc()
+			drain(resp)
+			return nil, &RateLimitError{Endpoint: endpoint, Reset: reset}
+		}
+
+		if !isTransient(resp.StatusCode) || !isIdempotent(req.Method) || attempt >= t.maxRetries {
+			return resp, nil
+		}
+		drain(resp)
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(t.retryBackoff << attempt):
+		}
+	}
+}
+
+// exhausted returns true and the time the rate limit resets, if the rate limit of the endpoint is exhausted.
+func (s *rateLimitStore) exhausted(org string, endpoint string) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	limit, ok := s.rateLimits[rateLimitKey{org: org, endpoint: endpoint}]
+	if !ok || limit.remaining > 0 || !time.Now().Before(limit.reset) {
+		return time.Time{}, false
+	}
+	return limit.reset, true
+}
+
+// update records the rate limit reported by the response and returns the time it resets.
+func (s *rateLimitStore) update(org string, endpoint string, resp *http.Response) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	limit := rateLimit{remaining: -1, reset: time.Now().Add(defaultRateLimitReset)}
+	if reset, err := strconv.ParseInt(resp.Header.Get("X-Rate-Limit-Reset"), 10, 64); err == nil {
+		limit.reset = time.Unix(reset, 0)
+	}
+	if remaining, err := strconv.Atoi(resp.Header.Get("X-Rate-Limit-Remaining")); err == nil {
+		limit.remaining = remaining
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		limit.remaining = 0
+	}
+
+	if limit.remaining >= 0 {
+		s.rateLimits[rateLimitKey{org: org, endpoint: endpoint}] = limit
+		rateLimitRemaining.WithLabelValues(org, endpoint).Set(float64(limit.remaining))
+	}
+	return limit.reset
+}
+
+// rateLimitEndpoint returns the endpoint the rate limit of the request applies to. Okta limits the rate of requests
+// per endpoint pattern, e.g. /api/v1/apps, /api/v1/apps/{id} and /api/v1/apps/{id}/credentials/secrets have separate
+// rate limits. IDs in the path are replaced by {id}, so all applications share the rate limit of /api/v1/apps/{id}.
+func rateLimitEndpoint(req *http.Request) string {
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+
+	// Collections and IDs alternate after the API version, e.g. /api/v1/apps/{id}/groups/{id}. Lifecycle operations
+	// and credentials are followed by an operation or a collection instead of an ID.
+	start := len(segments)
+	switch {
+	case len(segments) > 2 && (segments[0] == "api" || segments[0] == "oauth2") && segments[1] == "v1":
+		start = 2
+	case len(segments) > 3 && segments[0] == "oauth2" && segments[2] == "v1":
+		// Custom authorization servers, e.g. /oauth2/{id}/v1/token.
+		segments[1] = "{id}"
+		start = 3
+	}
+
+	id := false
+	for i := start; i < len(segments); i++ {
+		if id {
+			segments[i] = "{id}"
+			id = false
+			continue
+		}
+		id = segments[i] != "lifecycle" && segments[i] != "credentials"
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+// isTransient returns true for server errors that are likely to go away, when the request is retried.
+func isTransient(statusCode int) bool {
+	switch statusCode {
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// isIdempotent returns true, if the request can be repeated without side effects, e.g. creating an application twice.
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// drain and close the body of a response that is not returned, so its connection can be reused.
+func drain(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	_ = resp.Body.Close()
+}
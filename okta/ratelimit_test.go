@@ -0,0 +1,139 @@
+package okta
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	client, server := newTestClient(t)
+	server.RateLimit = 1
+	ctx := context.Background()
+
+	_, err := client.GetApplicationByLabel(ctx, "test-client")
+	if err != nil {
+		t.Fatalf("error getting application: %v", err)
+	}
+
+	// The fake limits all endpoints together. The rate limit of /api/v1/apps/{id} is exhausted, once the fake reports
+	// it. Further requests are not sent until it resets, even to other applications.
+	for i := 0; i < 2; i++ {
+		_, err = client.GetApplicationByID(ctx, "test-client")
+		retryAfter, rateLimited := RateLimitRetryAfter(err)
+		if !rateLimited || retryAfter <= 0 || retryAfter > time.Minute+time.Second {
+			t.Errorf("got error %v and retry after %s, wanted rate limit error", err, retryAfter)
+		}
+	}
+
+	// The rate limit of another endpoint is only exhausted, once it is reported by the fake. The discovery endpoints
+	// are not limited.
+	_, err = client.IsTrustedOrigin(ctx, "https://example.com")
+	if _, rateLimited := RateLimitRetryAfter(err); !rateLimited {
+		t.Errorf("got error %v, wanted rate limit error", err)
+	}
+	_, err = client.GetAuthorizationServerMetadata(ctx, "")
+	if err != nil {
+		t.Errorf("error getting metadata: %v", err)
+	}
+}
+
+func TestRateLimitShared(t *testing.T) {
+	requests := 0
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("X-Rate-Limit-Remaining", "0")
+		w.Header().Set("X-Rate-Limit-Reset", strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(httpServer.Close)
+
+	req, _ := http.NewRequest(http.MethodGet, httpServer.URL+"/api/v1/apps/a", nil)
+	_, err := newTransport(nil).RoundTrip(req)
+	if err != nil {
+		t.Fatalf("error sending request: %v", err)
+	}
+
+	// A new transport, e.g. of a reloaded client, knows the rate limit of the organization is exhausted.
+	req, _ = http.NewRequest(http.MethodGet, httpServer.URL+"/api/v1/apps/b", nil)
+	_, err = newTransport(nil).RoundTrip(req)
+	if _, rateLimited := RateLimitRetryAfter(err); !rateLimited || requests != 1 {
+		t.Errorf("got error %v after %d requests, wanted rate limit error after %d", err, requests, 1)
+	}
+
+	// Other endpoints have separate rate limits.
+	req, _ = http.NewRequest(http.MethodGet, httpServer.URL+"/api/v1/apps", nil)
+	_, err = newTransport(nil).RoundTrip(req)
+	if err != nil || requests != 2 {
+		t.Errorf("got error %v after %d requests, wanted success after %d", err, requests, 2)
+	}
+}
+
+func TestRateLimitEndpoint(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/api/v1/apps", want: "/api/v1/apps"},
+		{path: "/api/v1/apps/0oa1", want: "/api/v1/apps/{id}"},
+		{path: "/api/v1/apps/0oa1/groups/00g1", want: "/api/v1/apps/{id}/groups/{id}"},
+		{path: "/api/v1/apps/0oa1/lifecycle/deactivate", want: "/api/v1/apps/{id}/lifecycle/deactivate"},
+		{
+			path: "/api/v1/apps/0oa1/credentials/secrets/ocs1/lifecycle/deactivate",
+			want: "/api/v1/apps/{id}/credentials/secrets/{id}/lifecycle/deactivate",
+		},
+		{path: "/api/v1/trustedOrigins/tos1", want: "/api/v1/trustedOrigins/{id}"},
+		{path: "/oauth2/v1/clients/0oa1/lifecycle/newSecret", want: "/oauth2/v1/clients/{id}/lifecycle/newSecret"},
+		{path: "/oauth2/aus1/v1/token", want: "/oauth2/{id}/v1/token"},
+		{path: "/.well-known/openid-configuration", want: "/.well-known/openid-configuration"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.path, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "https://example.okta.com"+tt.path, nil)
+			if got := rateLimitEndpoint(req); got != tt.want {
+				t.Errorf("got endpoint %q, wanted %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryTransientError(t *testing.T) {
+	requests := 0
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(httpServer.Close)
+
+	transport := newTransport(nil)
+	transport.retryBackoff = time.Millisecond
+
+	req, _ := http.NewRequest(http.MethodDelete, httpServer.URL+"/api/v1/trustedOrigins/id", nil)
+	resp, err := transport.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusNoContent || requests != 3 {
+		t.Errorf("got response %v and error %v after %d requests, wanted success after %d", resp, err, requests, 3)
+	}
+
+	requests = 0
+	req, _ = http.NewRequest(http.MethodPost, httpServer.URL+"/api/v1/apps", nil)
+	resp, err = transport.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusServiceUnavailable || requests != 1 {
+		t.Errorf("got response %v and error %v after %d requests, wanted no retry", resp, err, requests)
+	}
+}
+
+func TestRateLimitRetryAfterOtherError(t *testing.T) {
+	_, rateLimited := RateLimitRetryAfter(context.Canceled)
+	if rateLimited {
+		t.Errorf("got rate limited for %v, wanted not rate limited", context.Canceled)
+	}
+}
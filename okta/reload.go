@@ -0,0 +1,307 @@
+package okta
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrClientUnavailable is returned by the calls of a ReloadingClient, whose credentials could not be loaded.
+var ErrClientUnavailable = errors.New("Okta client is unavailable")
+
+// CredentialFiles are files containing the credentials of an Okta organization, e.g. mounted from a secret. Empty paths
+// are ignored.
+type CredentialFiles struct {
+	// TokenFile contains the API token. Leading and trailing whitespace is ignored.
+	TokenFile string
+
+	// PrivateKeyFile contains the PEM encoded private key of the Okta service application.
+	PrivateKeyFile string
+}
+
+// ReloadingClient implements API using a Client, whose credentials are read from files. The files are read again
+// periodically and the client is replaced, once the credentials change. Kubernetes updates mounted secrets in place, so
+// credentials can be rotated without restarting the operator.
+//
+// Calls fail, until the credentials have been loaded successfully. If loading the credentials fails later on, the
+// previous client is kept. Either way, Check reports the error.
+type ReloadingClient struct {
+	config   Config
+	files    CredentialFiles
+	interval time.Duration
+
+	mu          sync.RWMutex
+	client      *Client
+	credentials Credentials
+	err         error
+}
+
+var _ API = &ReloadingClient{}
+
+// NewReloadingClient returns a client of the Okta organization described by the given config, reading its credentials
+// from the given files every interval. Credentials of the config are used, unless a file replaces them. The
+// credentials are loaded immediately, but errors are only reported by Check.
+func NewReloadingClient(ctx context.Context, config Config, files CredentialFiles, interval time.Duration) *ReloadingClient {
+	c := &ReloadingClient{config: config, files: files, interval: interval}
+	_ = c.Reload(ctx)
+	return c
+}
+
+// Reload reads the credential files and replaces the client, if the credentials changed or the previous client could
+// not be created.
+func (c *ReloadingClient) Reload(ctx context.Context) error {
+	credentials, err := c.readCredentials()
+	if err != nil {
+		return c.setError(err)
+	}
+
+	c.mu.RLock()
+	unchanged := c.client != nil && c.credentials.equal(credentials)
+	c.mu.RUnlock()
+	if unchanged {
+		return c.setError(nil)
+	}
+
+	config := c.config
+	config.Credentials = credentials
+	client, err := NewClient(ctx, config)
+	if err != nil {
+		return c.setError(err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.client = client
+	c.credentials = credentials
+	c.err = nil
+	return nil
+}
+
+// readCredentials returns the credentials of the config, replaced by the contents of the credential files.
+func (c *ReloadingClient) readCredentials() (Credentials, error) {
+	credentials := c.config.Credentials
+	if c.files.TokenFile != "" {
+		token, err := os.ReadFile(c.files.TokenFile)
+		if err != nil {
+			return Credentials{}, fmt.Errorf("failed to read Okta API token: %w", err)
+		}
+		credentials.Token = string(bytes.TrimSpace(token))
+	}
+	if c.files.PrivateKeyFile != "" {
+		privateKey, err := os.ReadFile(c.files.PrivateKeyFile)
+		if err != nil {
+			return Credentials{}, fmt.Errorf("failed to read private key of Okta service application: %w", err)
+		}
+		credentials.PrivateKey = privateKey
+	}
+	return credentials, nil
+}
+
+func (c *ReloadingClient) setError(err error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.err = err
+	return err
+}
+
+// Start reloads the credentials every interval, until the context is done. It implements manager.Runnable.
+func (c *ReloadingClient) Start(ctx context.Context) error {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			_ = c.Reload(ctx)
+		}
+	}
+}
+
+// NeedLeaderElection returns false, so credentials are reloaded by all replicas. It implements
+// manager.LeaderElectionRunnable.
+func (c *ReloadingClient) NeedLeaderElection() bool {
+	return false
+}
+
+// Check returns the error of the last reload. It implements healthz.Checker, so replicas without working credentials
+// are not ready.
+func (c *ReloadingClient) Check(_ *http.Request) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.err != nil {
+		return fmt.Errorf("failed to load Okta credentials: %w", c.err)
+	}
+	return nil
+}
+
+// current returns the current client or the error preventing its creation.
+func (c *ReloadingClient) current() (*Client, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.client == nil {
+		return nil, fmt.Errorf("%w: %w", ErrClientUnavailable, c.err)
+	}
+	return c.client, nil
+}
+
+// OrgURL returns the URL of the Okta organization. If the client is unavailable, the org URL of the config is returned.
+func (c *ReloadingClient) OrgURL() string {
+	client, err := c.current()
+	if err != nil {
+		return c.config.OrgURL
+	}
+	return client.OrgURL()
+}
+
+func (c *ReloadingClient) Ping(ctx context.Context) error {
+	client, err := c.current()
+	if err != nil {
+		return err
+	}
+	return client.Ping(ctx)
+}
+
+func (c *ReloadingClient) GetApplicationByID(ctx context.Context, id string) (*Application, error) {
+	client, err := c.current()
+	if err != nil {
+		return nil, err
+	}
+	return client.GetApplicationByID(ctx, id)
+}
+
+func (c *ReloadingClient) GetApplicationByLabel(ctx context.Context, label string) (*Application, error) {
+	client, err := c.current()
+	if err != nil {
+		return nil, err
+	}
+	return client.GetApplicationByLabel(ctx, label)
+}
+
+func (c *ReloadingClient) CreateApplication(ctx context.Context, settings ApplicationSettings) (*Application, error) {
+	client, err := c.current()
+	if err != nil {
+		return nil, err
+	}
+	return client.CreateApplication(ctx, settings)
+}
+
+func (c *ReloadingClient) UpdateApplication(ctx context.Context, app *Application, settings ApplicationSettings) error {
+	client, err := c.current()
+	if err != nil {
+		return err
+	}
+	return client.UpdateApplication(ctx, app, settings)
+}
+
+func (c *ReloadingClient) DeactivateApplication(ctx context.Context, app *Application) error {
+	client, err := c.current()
+	if err != nil {
+		return err
+	}
+	return client.DeactivateApplication(ctx, app)
+}
+
+func (c *ReloadingClient) DeleteApplication(ctx context.Context, app *Application) error {
+	client, err := c.current()
+	if err != nil {
+		return err
+	}
+	return client.DeleteApplication(ctx, app)
+}
+
+func (c *ReloadingClient) CreateApplicationGroupAssignment(ctx context.Context, app *Application, groupID string) error {
+	client, err := c.current()
+	if err != nil {
+		return err
+	}
+	return client.CreateApplicationGroupAssignment(ctx, app, groupID)
+}
+
+func (c *ReloadingClient) IsApplicationGroupAssigned(ctx context.Context, app *Application, groupID string) (bool, error) {
+	client, err := c.current()
+	if err != nil {
+		return false, err
+	}
+	return client.IsApplicationGroupAssigned(ctx, app, groupID)
+}
+
+func (c *ReloadingClient) NewSecret(ctx context.Context, clientID string) (string, error) {
+	client, err := c.current()
+	if err != nil {
+		return "", err
+	}
+	return client.NewSecret(ctx, clientID)
+}
+
+func (c *ReloadingClient) ListClientSecrets(ctx context.Context, app *Application) ([]ClientSecret, error) {
+	client, err := c.current()
+	if err != nil {
+		return nil, err
+	}
+	return client.ListClientSecrets(ctx, app)
+}
+
+func (c *ReloadingClient) CreateClientSecret(ctx context.Context, app *Application) (*ClientSecret, error) {
+	client, err := c.current()
+	if err != nil {
+		return nil, err
+	}
+	return client.CreateClientSecret(ctx, app)
+}
+
+func (c *ReloadingClient) DeleteClientSecret(ctx context.Context, app *Application, secretID string) error {
+	client, err := c.current()
+	if err != nil {
+		return err
+	}
+	return client.DeleteClientSecret(ctx, app, secretID)
+}
+
+func (c *ReloadingClient) IsTrustedOrigin(ctx context.Context, origin string) (bool, error) {
+	client, err := c.current()
+	if err != nil {
+		return false, err
+	}
+	return client.IsTrustedOrigin(ctx, origin)
+}
+
+func (c *ReloadingClient) CreateTrustedOrigin(ctx context.Context, origin string) error {
+	client, err := c.current()
+	if err != nil {
+		return err
+	}
+	return client.CreateTrustedOrigin(ctx, origin)
+}
+
+func (c *ReloadingClient) DeleteTrustedOrigin(ctx context.Context, origin string) error {
+	client, err := c.current()
+	if err != nil {
+		return err
+	}
+	return client.DeleteTrustedOrigin(ctx, origin)
+}
+
+func (c *ReloadingClient) DeactivateTrustedOrigin(ctx context.Context, origin string) error {
+	client, err := c.current()
+	if err != nil {
+		return err
+	}
+	return client.DeactivateTrustedOrigin(ctx, origin)
+}
+
+func (c *ReloadingClient) GetAuthorizationServerMetadata(ctx context.Context, authorizationServerID string) (*AuthorizationServerMetadata, error) {
+	client, err := c.current()
+	if err != nil {
+		return nil, err
+	}
+	return client.GetAuthorizationServerMetadata(ctx, authorizationServerID)
+}
@@ -0,0 +1,102 @@
+package okta
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jaconi-io/okta-operator/okta/oktatest"
+)
+
+func TestReloadingClient(t *testing.T) {
+	server := oktatest.NewServer("token")
+	httpServer := httptest.NewTLSServer(server)
+	t.Cleanup(httpServer.Close)
+	ctx := context.Background()
+
+	tokenFile := filepath.Join(t.TempDir(), "OKTA_CLIENT_TOKEN")
+	config := Config{OrgURL: httpServer.URL, HTTPClient: httpServer.Client()}
+	client := NewReloadingClient(ctx, config, CredentialFiles{TokenFile: tokenFile}, time.Minute)
+
+	// The secret is not mounted yet.
+	if err := client.Check(nil); err == nil {
+		t.Errorf("expected check to fail without token file")
+	}
+	if _, err := client.IsTrustedOrigin(ctx, "https://example.com"); err == nil {
+		t.Errorf("expected error calling the Okta API without token")
+	}
+	if client.OrgURL() != httpServer.URL {
+		t.Errorf("got org URL %q, wanted %q", client.OrgURL(), httpServer.URL)
+	}
+
+	err := os.WriteFile(tokenFile, []byte("token\n"), 0o600)
+	if err != nil {
+		t.Fatalf("error writing token file: %v", err)
+	}
+	err = client.Reload(ctx)
+	if err != nil || client.Check(nil) != nil {
+		t.Fatalf("error reloading token: %v", err)
+	}
+	if _, err := client.IsTrustedOrigin(ctx, "https://example.com"); err != nil {
+		t.Errorf("error calling the Okta API: %v", err)
+	}
+
+	// The token is rotated.
+	previous, _ := client.current()
+	server.Token = "rotated"
+	err = os.WriteFile(tokenFile, []byte("rotated"), 0o600)
+	if err != nil {
+		t.Fatalf("error writing token file: %v", err)
+	}
+	err = client.Reload(ctx)
+	if current, _ := client.current(); err != nil || current == previous {
+		t.Errorf("got error %v, wanted a new client for the rotated token", err)
+	}
+	if _, err := client.IsTrustedOrigin(ctx, "https://example.com"); err != nil {
+		t.Errorf("error calling the Okta API with the rotated token: %v", err)
+	}
+
+	// The previous client is kept, if the token file is removed.
+	err = os.Remove(tokenFile)
+	if err != nil {
+		t.Fatalf("error removing token file: %v", err)
+	}
+	if err := client.Reload(ctx); err == nil || client.Check(nil) == nil {
+		t.Errorf("expected error reloading removed token file")
+	}
+	if _, err := client.IsTrustedOrigin(ctx, "https://example.com"); err != nil {
+		t.Errorf("error calling the Okta API with the previous client: %v", err)
+	}
+}
+
+func TestReloadingClientStart(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "OKTA_CLIENT_TOKEN")
+	config := Config{OrgURL: "https://example.okta.com"}
+	client := NewReloadingClient(context.Background(), config, CredentialFiles{TokenFile: tokenFile}, 10*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- client.Start(ctx)
+	}()
+
+	err := os.WriteFile(tokenFile, []byte("token"), 0o600)
+	if err != nil {
+		t.Fatalf("error writing token file: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for client.Check(nil) != nil && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := client.Check(nil); err != nil {
+		t.Errorf("got error %v, wanted the token file to be reloaded", err)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("error stopping reloader: %v", err)
+	}
+}
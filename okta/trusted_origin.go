@@ -1,14 +1,15 @@
 package okta
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/okta/okta-sdk-golang/v2/okta"
 	"github.com/okta/okta-sdk-golang/v2/okta/query"
 )
 
-func IsTrustedOrigin(origin string) (bool, error) {
-	ctx, client := getContextAndClient()
+func (c *Client) IsTrustedOrigin(ctx context.Context, origin string) (bool, error) {
+	client := c.client
 
 	filter := query.NewQueryParams(query.WithFilter(fmt.Sprintf("origin eq %q", origin)), query.WithLimit(1))
 	origins, _, err := client.TrustedOrigin.ListOrigins(ctx, filter)
@@ -23,8 +24,8 @@ func IsTrustedOrigin(origin string) (bool, error) {
 	return true, nil
 }
 
-func CreateTrustedOrigin(origin string) error {
-	ctx, client := getContextAndClient()
+func (c *Client) CreateTrustedOrigin(ctx context.Context, origin string) error {
+	client := c.client
 
 	trustedOrigin := &okta.TrustedOrigin{
 		Name:   origin, // "https://{NAMESPACE}.zageno.{top_level_domain}"
@@ -40,8 +41,8 @@ func CreateTrustedOrigin(origin string) error {
 	return nil
 }
 
-func DeleteTrustedOrigin(origin string) error {
-	ctx, client := getContextAndClient()
+func (c *Client) DeleteTrustedOrigin(ctx context.Context, origin string) error {
+	client := c.client
 
 	filter := query.NewQueryParams(query.WithFilter(fmt.Sprintf("origin eq %q", origin)), query.WithLimit(1))
 	origins, _, err := client.TrustedOrigin.ListOrigins(ctx, filter)
@@ -56,3 +57,24 @@ func DeleteTrustedOrigin(origin string) error {
 
 	return nil
 }
+
+// DeactivateTrustedOrigin in Okta without deleting it.
+func (c *Client) DeactivateTrustedOrigin(ctx context.Context, origin string) error {
+	client := c.client
+
+	filter := query.NewQueryParams(query.WithFilter(fmt.Sprintf("origin eq %q", origin)), query.WithLimit(1))
+	origins, _, err := client.TrustedOrigin.ListOrigins(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("failed to get trusted origin %q for deactivation: %w", origin, err)
+	}
+	if len(origins) == 0 {
+		return nil
+	}
+
+	_, _, err = client.TrustedOrigin.DeactivateOrigin(ctx, origins[0].Id)
+	if err != nil {
+		return fmt.Errorf("failed to deactivate trusted origin %q: %w", origin, err)
+	}
+
+	return nil
+}